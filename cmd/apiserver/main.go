@@ -11,10 +11,12 @@ import (
 
 var (
 	configPath string
+	storeType  string
 )
 
 func init() {
 	flag.StringVar(&configPath, "config-path", "configs/apiserver.toml", "path to config file")
+	flag.StringVar(&storeType, "store", "", "where to keep data: sql or memory (overrides the config file)")
 }
 
 func main() {
@@ -32,6 +34,17 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if storeType != "" {
+		config.Store = storeType
+	}
+
+	if flag.Arg(0) == "migrate" {
+		if err := migrate(config, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := apiserver.Start(config); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/apiserver"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store/sqlstore"
+)
+
+const migrateUsage = "usage: spyfall migrate up | down | status | to <version>"
+
+// migrate runs the migrate subcommand against the configured database
+func migrate(config *apiserver.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := sql.Open("postgres", config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := sqlstore.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = migrator.Up()
+	case args[0] == "down" && len(args) == 1:
+		err = migrator.Down()
+	case args[0] == "to" && len(args) == 2:
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("Version must be a number: %w", convErr)
+		}
+		err = migrator.To(version)
+	case args[0] == "status" && len(args) == 1:
+		return printStatus(migrator)
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Schema is at version %d of %d\n", version, migrator.Latest())
+	return nil
+}
+
+// printStatus lists the migrations and when they were applied
+func printStatus(migrator *sqlstore.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range status {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%04d %-30s %s\n", s.Version, s.Name, applied)
+	}
+
+	return nil
+}
@@ -3,29 +3,53 @@ package apiserver
 import (
 	"database/sql"
 	"net/http"
+	"time"
 
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store/memstore"
 	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store/sqlstore"
 )
 
 // Start func
 func Start(config *Config) error {
+	if config.Store == "memory" {
+		store := memstore.New()
+		defer store.Listener().Close()
+
+		srv := newServer(store, store.Listener(), config)
+
+		return http.ListenAndServe(config.BindAddr, srv)
+	}
+
 	db, err := newDB(config.DatabaseURL)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	store := sqlstore.New(db)
+	if config.AutoMigrate {
+		migrator, err := sqlstore.NewMigrator(db)
+		if err != nil {
+			return err
+		}
+
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	}
 
-	srv := newServer(store)
+	store := sqlstore.New(db)
+	store.SetTimeouts(sqlstore.Timeouts{
+		Read:  time.Duration(config.ReadTimeout) * time.Millisecond,
+		Write: time.Duration(config.WriteTimeout) * time.Millisecond,
+		Tx:    time.Duration(config.TxTimeout) * time.Millisecond,
+	})
 
-	go func() {
-		http.ListenAndServe(config.BindAddr, srv)
-	}()
+	listener := sqlstore.NewListener(config.DatabaseURL)
+	defer listener.Close()
 
-	select {}
+	srv := newServer(store, listener, config)
 
-	return nil
+	return http.ListenAndServe(config.BindAddr, srv)
 }
 
 // newDB func
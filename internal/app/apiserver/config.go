@@ -5,12 +5,39 @@ type Config struct {
 	BindAddr    string `toml:"bind_addr"`
 	LogLevel    string `toml:"log_level"`
 	DatabaseURL string `toml:"database_url"`
+	// Store is "sql" to keep data in Postgres or "memory" to keep it in the
+	// server process, which needs no database and loses everything on exit
+	Store string `toml:"store"`
+	// AutoMigrate applies the pending schema migrations when the server starts
+	AutoMigrate bool `toml:"auto_migrate"`
+	// InviteCodeLength is the number of hex characters in a lobby token
+	InviteCodeLength int `toml:"invite_code_length"`
+	// JoinAttempts is the number of failed joins and lookups of unknown lobbies
+	// allowed per client address within JoinAttemptWindow seconds
+	JoinAttempts      int `toml:"join_attempts"`
+	JoinAttemptWindow int `toml:"join_attempt_window"`
+	// TrustedProxies are the addresses or CIDR networks of the reverse
+	// proxies whose X-Forwarded-For and X-Real-IP headers name the client
+	TrustedProxies []string `toml:"trusted_proxies"`
+	// ReadTimeout, WriteTimeout and TxTimeout bound in milliseconds how long
+	// a database lookup, a database change and a whole unit of work may take,
+	// 0 meaning no bound
+	ReadTimeout  int `toml:"read_timeout"`
+	WriteTimeout int `toml:"write_timeout"`
+	TxTimeout    int `toml:"tx_timeout"`
 }
 
 // NewConfig func
 func NewConfig() *Config {
 	return &Config{
-		BindAddr: "*:8080",
-		LogLevel: "debug",
+		BindAddr:          "*:8080",
+		LogLevel:          "debug",
+		Store:             "sql",
+		InviteCodeLength:  12,
+		JoinAttempts:      10,
+		JoinAttemptWindow: 60,
+		ReadTimeout:       2000,
+		WriteTimeout:      5000,
+		TxTimeout:         10000,
 	}
 }
@@ -0,0 +1,99 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+)
+
+// Error codes returned alongside game rule violations
+const (
+	codeIllegalTransition = "illegal_transition"
+	codeLobbyFull         = "lobby_full"
+	codeAlreadyJoined     = "already_joined"
+	codeAlreadyVoted      = "already_voted"
+	codeNotMember         = "not_a_member"
+	codeNotHost           = "not_host"
+	codeBanned            = "banned"
+	codeNotEnoughPlayers  = "not_enough_players"
+	codeTooManySpies      = "too_many_spies"
+	codeNotReady          = "not_ready"
+	codeWrongPassword     = "wrong_password"
+	codeTooManyAttempts   = "too_many_attempts"
+	codeResync            = "resync"
+	codeLobbyClosed       = "lobby_closed"
+)
+
+var (
+	errNotAuthenticated = errors.New("You are not logged in")
+	errNotMember        = errors.New("You are not a member of this lobby")
+	errNotHost          = errors.New("Only the lobby host can do that")
+	errWrongPassword    = errors.New("Wrong lobby password")
+	errTooManyAttempts  = errors.New("Too many failed attempts, try again later")
+	errResync           = errors.New("Missed events are no longer available, reload the lobby")
+	errLobbyClosed      = errors.New("The lobby has been closed")
+)
+
+// refusal is a request turned down with a message rather than an error status
+type refusal string
+
+func (r refusal) Error() string {
+	return string(r)
+}
+
+// respondLobbyError responds to a failed lobby action: refusals with their
+// message and the rest as respondError
+func respondLobbyError(w http.ResponseWriter, fallback int, err error) {
+	var refused refusal
+	if errors.As(err, &refused) {
+		response := u.Message(false, string(refused))
+		u.Respond(w, response)
+		return
+	}
+
+	respondError(w, fallback, err)
+}
+
+// respondError writes err with its own status and code when it is a game rule
+// violation, and with the fallback status otherwise
+func respondError(w http.ResponseWriter, fallback int, err error) {
+	switch {
+	case errors.Is(err, model.ErrIllegalTransition):
+		u.ErrorCode(w, http.StatusConflict, codeIllegalTransition, err)
+	case errors.Is(err, store.ErrLobbyFull):
+		u.ErrorCode(w, http.StatusConflict, codeLobbyFull, err)
+	case errors.Is(err, store.ErrAlreadyJoined):
+		u.ErrorCode(w, http.StatusConflict, codeAlreadyJoined, err)
+	case errors.Is(err, store.ErrAlreadyVoted):
+		u.ErrorCode(w, http.StatusConflict, codeAlreadyVoted, err)
+	case errors.Is(err, model.ErrNotEnoughPlayers):
+		u.ErrorCode(w, http.StatusConflict, codeNotEnoughPlayers, err)
+	case errors.Is(err, model.ErrTooManySpies):
+		u.ErrorCode(w, http.StatusConflict, codeTooManySpies, err)
+	case errors.Is(err, model.ErrNotReady):
+		u.ErrorCode(w, http.StatusConflict, codeNotReady, err)
+	case errors.Is(err, store.ErrBanned):
+		u.ErrorCode(w, http.StatusForbidden, codeBanned, err)
+	case errors.Is(err, errWrongPassword):
+		u.ErrorCode(w, http.StatusForbidden, codeWrongPassword, err)
+	case errors.Is(err, errTooManyAttempts):
+		u.ErrorCode(w, http.StatusTooManyRequests, codeTooManyAttempts, err)
+	case errors.Is(err, errLobbyClosed):
+		u.ErrorCode(w, http.StatusConflict, codeLobbyClosed, err)
+	case errors.Is(err, errResync):
+		u.ErrorCode(w, http.StatusGone, codeResync, err)
+	case errors.Is(err, store.ErrRecordNotFound):
+		u.Error(w, http.StatusNotFound, err)
+	case errors.Is(err, errNotAuthenticated):
+		u.Error(w, http.StatusUnauthorized, err)
+	case errors.Is(err, errNotMember):
+		u.ErrorCode(w, http.StatusForbidden, codeNotMember, err)
+	case errors.Is(err, errNotHost):
+		u.ErrorCode(w, http.StatusForbidden, codeNotHost, err)
+	default:
+		u.Error(w, fallback, err)
+	}
+}
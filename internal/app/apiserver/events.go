@@ -0,0 +1,147 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/jwt"
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+	"github.com/gorilla/mux"
+)
+
+// heartbeatPeriod is how often an idle event stream sends a comment line
+// so that proxies do not close the connection.
+const heartbeatPeriod = 15 * time.Second
+
+// lobbyEvents func
+func (s *server) lobbyEvents() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// EventSource can't set headers, so the auth token may come in the
+		// query string
+		r, err := jwt.Authenticate(r)
+		if err != nil {
+			u.Error(w, http.StatusForbidden, err)
+			return
+		}
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			u.Error(w, http.StatusInternalServerError, errors.New("Streaming is not supported"))
+			return
+		}
+
+		currentlobby, err := s.store.Lobby().FindByToken(r.Context(), token)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		user, err := s.lobbyMember(r, currentlobby)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		sub, missed, err := s.resume(r.Context(), token, user.Login, lastEventID(r))
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		defer s.unsubscribe(token, sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		for _, e := range missed {
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(heartbeatPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case e := <-sub.events:
+				if err := writeEvent(w, e); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// resume subscribes login to the lobby and returns the events they missed
+// since lastID. It fails with errResync when this server can't tell which
+// events those are, for instance after the client reconnected to another
+// server, and the client has to reload the lobby and subscribe afresh.
+func (s *server) resume(ctx context.Context, token, login string, lastID int64) (*subscriber, []*event, error) {
+	sub, missed, covered := s.subscribe(token, login, lastID)
+	if covered {
+		return sub, missed, nil
+	}
+
+	// A client that saw the last change of the lobby has missed nothing. The
+	// lobby is read after subscribing, so no later change can slip by.
+	l, err := s.store.Lobby().FindByToken(ctx, token)
+	if err == nil && lastID/eventsPerChange == l.EventSeq {
+		return sub, missed, nil
+	}
+
+	s.unsubscribe(token, sub)
+
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, errResync
+}
+
+// writeEvent writes e in the text/event-stream format
+func writeEvent(w http.ResponseWriter, e *event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// lastEventID reads the id of the last event the client has seen from the
+// Last-Event-ID header, falling back to the last_event_id query parameter
+func lastEventID(r *http.Request) int64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+
+	return id
+}
@@ -0,0 +1,270 @@
+package apiserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	"github.com/sirupsen/logrus"
+)
+
+// Event types pushed to lobby subscribers
+const (
+	eventPlayerJoined = "player_joined"
+	eventGameStarted  = "game_started"
+	eventRole         = "role"
+	eventGameEnded    = "game_ended"
+	eventAccusation   = "accusation"
+	eventVote         = "vote"
+	eventAcquittal    = "acquittal"
+	eventClock        = "clock"
+	eventTimeUp       = "time_up"
+	eventPlayerLeft   = "player_left"
+	eventPlayerKicked = "player_kicked"
+	eventUnbanned     = "unbanned"
+	eventReady        = "ready"
+	eventPresence     = "presence"
+	eventWatching     = "watching"
+	eventUnwatched    = "unwatched"
+	eventHostChanged  = "host_changed"
+	eventSettings     = "settings"
+	eventLobbyClosed  = "lobby_closed"
+)
+
+const (
+	// subscriberBuffer is the number of events queued for a subscriber
+	// before further events are dropped for it.
+	subscriberBuffer = 32
+	// historySize is the number of past events kept per lobby for replay.
+	historySize = 256
+	// channelTTL is how long an idle lobby channel keeps its history.
+	channelTTL = time.Hour
+	// eventsPerChange spaces out the ids of the events of consecutive lobby
+	// changes. It must exceed the events a change fans out to, which is one
+	// for everyone and one per player.
+	eventsPerChange = 100
+)
+
+// event struct. Its ID is derived from the store's sequence number of the
+// lobby change it reports, so it is the same on every server.
+type event struct {
+	ID   int64       `json:"id"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+
+	// recipient is the only login the event is delivered to, empty for everyone
+	recipient string
+}
+
+// visibleTo func
+func (e *event) visibleTo(login string) bool {
+	return e.recipient == "" || e.recipient == login
+}
+
+// subscriber struct
+type subscriber struct {
+	login  string
+	events chan *event
+	// after is the id of the last event the subscriber has seen already
+	after int64
+}
+
+// channel struct
+type channel struct {
+	subscribers map[*subscriber]struct{}
+	history     []*event
+	// seq is the sequence number of the last lobby change published and part
+	// the number of events published for it so far
+	seq        int64
+	part       int64
+	lastActive time.Time
+}
+
+// hub struct
+type hub struct {
+	mu       sync.Mutex
+	channels map[string]*channel
+	// listening serializes the calls to listener, which are made without
+	// holding mu
+	listening sync.Mutex
+	listener  store.Listener
+	logger    *logrus.Logger
+}
+
+func newHub(listener store.Listener, logger *logrus.Logger) *hub {
+	return &hub{
+		channels: make(map[string]*channel),
+		listener: listener,
+		logger:   logger,
+	}
+}
+
+// channel returns the lobby channel, creating it if needed, along with
+// whether it was created and the tokens of idle channels that were dropped.
+// Caller must hold h.mu.
+func (h *hub) channel(token string) (*channel, bool, []string) {
+	now := time.Now()
+
+	var dropped []string
+	for t, c := range h.channels {
+		if t != token && len(c.subscribers) == 0 && now.Sub(c.lastActive) > channelTTL {
+			delete(h.channels, t)
+			dropped = append(dropped, t)
+		}
+	}
+
+	c, ok := h.channels[token]
+	if !ok {
+		c = &channel{
+			subscribers: make(map[*subscriber]struct{}),
+		}
+		h.channels[token] = c
+	}
+	c.lastActive = now
+
+	return c, !ok, dropped
+}
+
+// sync starts listening for changes of a newly opened lobby channel
+// and stops listening for the dropped ones. A dropped lobby whose channel was
+// opened again in the meantime stays listened to: its subscriber listens
+// either before, which is then kept, or after, as the calls are serialized.
+func (h *hub) sync(token string, created bool, dropped []string) {
+	if !created && len(dropped) == 0 {
+		return
+	}
+
+	h.listening.Lock()
+	defer h.listening.Unlock()
+
+	if created {
+		if err := h.listener.Listen(token); err != nil {
+			h.logger.Errorf("unable to listen to lobby %s: %v", token, err)
+		}
+	}
+
+	for _, t := range dropped {
+		h.mu.Lock()
+		_, reopened := h.channels[t]
+		h.mu.Unlock()
+
+		if reopened {
+			continue
+		}
+
+		if err := h.listener.Unlisten(t); err != nil {
+			h.logger.Errorf("unable to unlisten lobby %s: %v", t, err)
+		}
+	}
+}
+
+// subscribe registers a subscriber for the lobby and returns the events
+// visible to login that were published after lastID. It also reports
+// whether the history kept by this server reaches back to lastID, which it
+// doesn't if the client last saw an event before the server started
+// following the lobby or before its oldest kept event.
+func (h *hub) subscribe(token, login string, lastID int64) (*subscriber, []*event, bool) {
+	h.mu.Lock()
+
+	c, created, dropped := h.channel(token)
+
+	sub := &subscriber{
+		login:  login,
+		events: make(chan *event, subscriberBuffer),
+		after:  lastID,
+	}
+	c.subscribers[sub] = struct{}{}
+
+	var missed []*event
+	covered := lastID == 0
+	if lastID > 0 {
+		covered = len(c.history) > 0 && c.history[0].ID <= lastID
+		for _, e := range c.history {
+			if e.ID > lastID && e.visibleTo(login) {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	h.mu.Unlock()
+
+	h.sync(token, created, dropped)
+
+	return sub, missed, covered
+}
+
+// unsubscribe func
+func (h *hub) unsubscribe(token string, sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c, ok := h.channels[token]; ok {
+		delete(c.subscribers, sub)
+		c.lastActive = time.Now()
+	}
+}
+
+// present returns the logins subscribed to each lobby
+func (h *hub) present() map[string][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	present := make(map[string][]string)
+	for token, c := range h.channels {
+		seen := make(map[string]bool)
+		for sub := range c.subscribers {
+			if sub.login != "" && !seen[sub.login] {
+				seen[sub.login] = true
+				present[token] = append(present[token], sub.login)
+			}
+		}
+	}
+	return present
+}
+
+// publish sends the event reporting the lobby change numbered seq to every
+// subscriber of the lobby
+func (h *hub) publish(token string, seq int64, e *event) {
+	h.send(token, seq, e)
+}
+
+// publishTo sends the event reporting the lobby change numbered seq only to
+// subscribers identified as login
+func (h *hub) publishTo(token string, seq int64, login string, e *event) {
+	e.recipient = login
+	h.send(token, seq, e)
+}
+
+func (h *hub) send(token string, seq int64, e *event) {
+	h.mu.Lock()
+
+	c, created, dropped := h.channel(token)
+
+	if seq != c.seq {
+		c.seq = seq
+		c.part = 0
+	} else {
+		c.part++
+	}
+	e.ID = seq*eventsPerChange + c.part
+
+	c.history = append(c.history, e)
+	if len(c.history) > historySize {
+		c.history = c.history[len(c.history)-historySize:]
+	}
+
+	for sub := range c.subscribers {
+		if !e.visibleTo(sub.login) || e.ID <= sub.after {
+			continue
+		}
+
+		select {
+		case sub.events <- e:
+		default:
+		}
+	}
+
+	h.mu.Unlock()
+
+	h.sync(token, created, dropped)
+}
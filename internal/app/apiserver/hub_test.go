@@ -0,0 +1,64 @@
+package apiserver
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	"github.com/sirupsen/logrus"
+)
+
+// testListener records the lobbies listened to
+type testListener struct {
+	mu     sync.Mutex
+	tokens map[string]bool
+}
+
+func (l *testListener) Listen(token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens[token] = true
+	return nil
+}
+
+func (l *testListener) Unlisten(token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.tokens, token)
+	return nil
+}
+
+func (l *testListener) Notifications() <-chan *store.Notification {
+	return nil
+}
+
+func (l *testListener) Close() error {
+	return nil
+}
+
+func TestHub_ReopenedChannelStaysListened(t *testing.T) {
+	listener := &testListener{tokens: make(map[string]bool)}
+	h := newHub(listener, logrus.New())
+
+	sub, _, _ := h.subscribe("idle", "player", 0)
+	h.unsubscribe("idle", sub)
+
+	h.mu.Lock()
+	h.channels["idle"].lastActive = time.Now().Add(-2 * channelTTL)
+	_, created, dropped := h.channel("other")
+	h.mu.Unlock()
+
+	// A subscriber reopens the dropped channel before it is unlistened
+	h.subscribe("idle", "player", 0)
+	h.sync("other", created, dropped)
+
+	if !listener.tokens["idle"] {
+		t.Fatal("reopened lobby is no longer listened to")
+	}
+	if !listener.tokens["other"] {
+		t.Fatal("new lobby is not listened to")
+	}
+}
@@ -0,0 +1,351 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+	"github.com/gorilla/mux"
+)
+
+// lobbySettings are the match settings chosen by the host
+type lobbySettings struct {
+	MinPl         int `json:"minpl"`
+	AmountPl      int `json:"amountpl"`
+	AmountSpy     int `json:"amountspy"`
+	RoundDuration int `json:"roundduration"`
+	Rounds        int `json:"rounds"`
+}
+
+// normalize fills in the defaults and returns a message explaining the first
+// invalid setting, or "" if the settings are valid
+func (ls *lobbySettings) normalize() string {
+	if ls.MinPl == 0 {
+		ls.MinPl = model.MinPlayers
+	}
+
+	if ls.AmountPl == 0 {
+		ls.AmountPl = defaultMaxPlayers
+	}
+
+	if ls.MinPl < model.MinPlayers || ls.AmountPl > model.MaxPlayers || ls.MinPl > ls.AmountPl {
+		return fmt.Sprintf("A lobby takes from %d to %d players, with the minimum not above the maximum", model.MinPlayers, model.MaxPlayers)
+	}
+
+	if ls.AmountSpy < 0 || ls.AmountSpy >= ls.MinPl {
+		return fmt.Sprintf("There must be fewer spies than the minimum of %d players", ls.MinPl)
+	}
+
+	if ls.RoundDuration == 0 {
+		ls.RoundDuration = defaultRoundDuration
+	}
+
+	if ls.RoundDuration < minRoundDuration || ls.RoundDuration > maxRoundDuration {
+		return fmt.Sprintf("Round duration must be between %d and %d seconds", minRoundDuration, maxRoundDuration)
+	}
+
+	if ls.Rounds == 0 {
+		ls.Rounds = 1
+	}
+
+	if ls.Rounds < 1 || ls.Rounds > maxRounds {
+		return fmt.Sprintf("A match can have from 1 to %d rounds", maxRounds)
+	}
+
+	return ""
+}
+
+// updateSettings func
+func (s *server) updateSettings() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		req := &lobbySettings{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		_, currentlobby, err := s.withLobby(r, token, checkHost, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			if message := req.normalize(); message != "" {
+				return refusal(message)
+			}
+
+			if req.AmountPl < len(l.AllPlayers) {
+				return refusal(fmt.Sprintf("%d players have joined already", len(l.AllPlayers)))
+			}
+
+			l.MinPl = req.MinPl
+			l.AmountPl = req.AmountPl
+			l.AmountSpy = req.AmountSpy
+			l.RoundDuration = req.RoundDuration
+			l.Rounds = req.Rounds
+
+			return tx.Lobby().UpdateSettings(r.Context(), l)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Settings have been saved")
+		response["lobby"] = currentlobby.ViewFor(currentlobby.Host)
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// setReady func
+func (s *server) setReady() http.HandlerFunc {
+
+	type request struct {
+		Ready bool `json:"ready"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		_, currentlobby, err := s.withLobby(r, token, checkPlayer, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			return tx.Lobby().SetReady(r.Context(), l, user.Login, req.Ready)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Ready state has been saved")
+		response["ready"] = currentlobby.Ready
+		response["unready"] = currentlobby.Unready()
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// transferHost func
+func (s *server) transferHost() http.HandlerFunc {
+
+	type request struct {
+		Login string `json:"login"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		_, currentlobby, err := s.withLobby(r, token, checkHost, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			if req.Login == user.Login || !u.Contains(l.AllPlayers, req.Login) {
+				return refusal("The new host must be another player of this lobby")
+			}
+
+			return tx.Lobby().TransferHost(r.Context(), l, req.Login)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Host has been transferred")
+		response["host"] = currentlobby.Host
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// leaveLobby func
+func (s *server) leaveLobby() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		_, currentlobby, err := s.withLobby(r, token, checkPlayer, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			return tx.Lobby().LeaveLobby(r.Context(), l, user.Login)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "You have left the lobby")
+		response["host"] = currentlobby.Host
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// watchLobby joins the authenticated user to the lobby as a spectator
+func (s *server) watchLobby() http.HandlerFunc {
+
+	type request struct {
+		Password string `json:"password"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		user, currentlobby, err := s.withLobby(r, token, admit(req.Password), func(tx store.Store, user *model.User, l *model.Lobby) error {
+			if u.Contains(l.Spectators, user.Login) {
+				return nil
+			}
+
+			return tx.Lobby().WatchLobby(r.Context(), l, user.Login)
+		})
+		if err != nil {
+			s.failWrongPassword(r, err)
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "You are watching the lobby")
+		response["scoreboard"] = currentlobby.Scoreboard()
+		response["lobby"] = currentlobby.ViewFor(user.Login)
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// stopWatching removes the authenticated user from the lobby spectators
+func (s *server) stopWatching() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		_, currentlobby, err := s.withLobby(r, token, checkSpectator, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			return tx.Lobby().StopWatching(r.Context(), l, user.Login)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "You have stopped watching the lobby")
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// kickPlayer removes a player from the lobby and bans them from rejoining
+func (s *server) kickPlayer() http.HandlerFunc {
+
+	type request struct {
+		Login string `json:"login"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		_, currentlobby, err := s.withLobby(r, token, checkHost, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			if req.Login == user.Login || !u.Contains(l.AllPlayers, req.Login) {
+				return refusal("You can only kick another player of this lobby")
+			}
+
+			return tx.Lobby().KickPlayer(r.Context(), l, req.Login)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Player has been kicked")
+		response["players"] = currentlobby.AllPlayers
+		response["banned"] = currentlobby.Banned
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// unbanPlayer func
+func (s *server) unbanPlayer() http.HandlerFunc {
+
+	type request struct {
+		Login string `json:"login"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		_, currentlobby, err := s.withLobby(r, token, checkHost, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			return tx.Lobby().UnbanPlayer(r.Context(), l, req.Login)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Player has been unbanned")
+		response["banned"] = currentlobby.Banned
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// endGame func
+func (s *server) endGame() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		_, currentlobby, err := s.withLobby(r, token, checkHost, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			return tx.Lobby().EndGame(r.Context(), l)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.scheduleRound(currentlobby)
+
+		response := u.Message(true, "Game has been ended")
+		response["scoreboard"] = currentlobby.Scoreboard()
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
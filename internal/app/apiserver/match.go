@@ -0,0 +1,147 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+)
+
+// Page sizes of the lobby browser
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// quickMatchPages is how many pages of open lobbies quick match tries
+// before it creates a new lobby
+const quickMatchPages = 5
+
+// listLobbies func
+func (s *server) listLobbies() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		query := r.URL.Query()
+
+		page, err := queryInt(query.Get("page"), 1)
+		if err != nil || page < 1 {
+			u.Error(w, http.StatusBadRequest, errors.New("Page must be a positive number"))
+			return
+		}
+
+		limit, err := queryInt(query.Get("limit"), defaultPageSize)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			u.Error(w, http.StatusBadRequest, errors.New("Limit must be between 1 and 100"))
+			return
+		}
+
+		lobbies, err := s.store.Lobby().FindOpen(r.Context(), limit, (page-1)*limit)
+		if err != nil {
+			u.Error(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Lobbies")
+		response["lobbies"] = lobbies
+		response["page"] = page
+		response["limit"] = limit
+		u.Respond(w, response)
+	}
+}
+
+// quickMatch drops the authenticated user into the fullest open public lobby
+// that accepts them, or creates a public lobby for them if there is none
+func (s *server) quickMatch() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, err := s.currentUser(r)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		for page := 0; page < quickMatchPages; page++ {
+			candidates, err := s.store.Lobby().FindOpen(r.Context(), defaultPageSize, page*defaultPageSize)
+			if err != nil {
+				u.Error(w, http.StatusUnprocessableEntity, err)
+				return
+			}
+
+			for _, candidate := range candidates {
+				currentlobby, err := s.store.Lobby().FindByToken(r.Context(), candidate.Token)
+				if err != nil {
+					continue
+				}
+
+				if u.Contains(currentlobby.AllPlayers, user.Login) {
+					respondMatch(w, currentlobby, user.Login, "You are already in this lobby")
+					return
+				}
+
+				err = s.store.Lobby().ConnectUserToLobby(r.Context(), currentlobby, user.Login)
+				switch {
+				case err == nil:
+					respondMatch(w, currentlobby, user.Login, "You have joined a lobby")
+					return
+				case errors.Is(err, store.ErrLobbyFull), errors.Is(err, store.ErrBanned), errors.Is(err, store.ErrAlreadyJoined), errors.Is(err, model.ErrIllegalTransition):
+					continue
+				default:
+					u.Error(w, http.StatusUnprocessableEntity, err)
+					return
+				}
+			}
+
+			if len(candidates) < defaultPageSize {
+				break
+			}
+		}
+
+		settings := &lobbySettings{}
+		settings.normalize()
+
+		currentlobby := &model.Lobby{
+			Host:          user.Login,
+			MinPl:         settings.MinPl,
+			AmountPl:      settings.AmountPl,
+			AmountSpy:     settings.AmountSpy,
+			RoundDuration: settings.RoundDuration,
+			Rounds:        settings.Rounds,
+			Public:        true,
+		}
+
+		err = s.store.WithTx(r.Context(), func(tx store.Store) error {
+			if err := s.openLobby(r.Context(), tx, currentlobby, model.DefaultLocations, defaultBoardSize); err != nil {
+				return err
+			}
+
+			return tx.Lobby().ConnectUserToLobby(r.Context(), currentlobby, user.Login)
+		})
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		respondMatch(w, currentlobby, user.Login, "A new lobby has been created for you")
+	}
+}
+
+// respondMatch func
+func respondMatch(w http.ResponseWriter, l *model.Lobby, login, message string) {
+	response := u.Message(true, message)
+	response["token"] = l.Token
+	response["lobby"] = l.ViewFor(login)
+	u.Respond(w, withClock(response, l))
+}
+
+// queryInt parses a query parameter, returning fallback when it is missing
+func queryInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,204 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+	"github.com/gorilla/mux"
+)
+
+// createPack func
+func (s *server) createPack() http.HandlerFunc {
+
+	type request struct {
+		Name      string           `json:"name"`
+		Locations []model.Location `json:"locations"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.currentUser(r)
+		if err != nil {
+			u.Error(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		pack := &model.Pack{
+			OwnerID:   user.ID,
+			Name:      req.Name,
+			Locations: req.Locations,
+		}
+
+		if response, ok := pack.Validate(); !ok {
+			u.Respond(w, response)
+			return
+		}
+
+		if err := s.store.Pack().Create(r.Context(), pack); err != nil {
+			u.Error(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Pack has been created")
+		response["pack"] = pack
+		u.Respond(w, response)
+	}
+}
+
+// getPack func
+func (s *server) getPack() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		pack, err := s.store.Pack().Find(r.Context(), id)
+		if err == store.ErrRecordNotFound {
+			u.Error(w, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			u.Error(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Pack")
+		response["pack"] = pack
+		u.Respond(w, response)
+	}
+}
+
+// listPacks func
+func (s *server) listPacks() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, err := s.currentUser(r)
+		if err != nil {
+			u.Error(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		packs, err := s.store.Pack().FindByOwner(r.Context(), user.ID)
+		if err != nil {
+			u.Error(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Packs")
+		response["packs"] = packs
+		u.Respond(w, response)
+	}
+}
+
+// updatePack func
+func (s *server) updatePack() http.HandlerFunc {
+
+	type request struct {
+		Name      string           `json:"name"`
+		Locations []model.Location `json:"locations"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		pack, ok := s.ownPack(w, r)
+		if !ok {
+			return
+		}
+
+		pack.Name = req.Name
+		pack.Locations = req.Locations
+
+		if response, ok := pack.Validate(); !ok {
+			u.Respond(w, response)
+			return
+		}
+
+		if err := s.store.Pack().Update(r.Context(), pack); err != nil {
+			u.Error(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Pack has been updated")
+		response["pack"] = pack
+		u.Respond(w, response)
+	}
+}
+
+// deletePack func
+func (s *server) deletePack() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		pack, ok := s.ownPack(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.store.Pack().Delete(r.Context(), pack.ID); err != nil {
+			u.Error(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Pack has been deleted")
+		u.Respond(w, response)
+	}
+}
+
+// ownPack loads the pack of the request path and checks that it belongs to
+// the authenticated user, writing the error response otherwise
+func (s *server) ownPack(w http.ResponseWriter, r *http.Request) (*model.Pack, bool) {
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		u.Error(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	user, err := s.currentUser(r)
+	if err != nil {
+		u.Error(w, http.StatusUnauthorized, err)
+		return nil, false
+	}
+
+	pack, err := s.store.Pack().Find(r.Context(), id)
+	if err == store.ErrRecordNotFound {
+		u.Error(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	if err != nil {
+		u.Error(w, http.StatusUnprocessableEntity, err)
+		return nil, false
+	}
+
+	if pack.OwnerID != user.ID {
+		response := u.Message(false, "Only the owner can change this pack")
+		w.WriteHeader(http.StatusForbidden)
+		u.Respond(w, response)
+		return nil, false
+	}
+
+	return pack, true
+}
@@ -0,0 +1,100 @@
+package apiserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+)
+
+// gracePeriod is how long a player may stay without any connection to the
+// lobby before they are shown as disconnected
+const gracePeriod = 30 * time.Second
+
+// heartbeatInterval is how often the server records in the store that the
+// players subscribed to it are still connected
+const heartbeatInterval = gracePeriod / 3
+
+// subscribe subscribes login to the lobby and marks them as connected. See
+// hub.subscribe for the events it returns.
+func (s *server) subscribe(token, login string, lastID int64) (*subscriber, []*event, bool) {
+	sub, missed, covered := s.hub.subscribe(token, login, lastID)
+
+	if login != "" {
+		if err := s.store.Lobby().Heartbeat(context.Background(), token, []string{login}); err != nil {
+			s.logger.Errorf("unable to record presence of %s in lobby %s: %v", login, token, err)
+		}
+		s.setOnline(token, login, true)
+	}
+
+	return sub, missed, covered
+}
+
+// unsubscribe drops the subscription. Its login is marked as disconnected by
+// trackPresence once no server has seen them for the grace period.
+func (s *server) unsubscribe(token string, sub *subscriber) {
+	s.hub.unsubscribe(token, sub)
+}
+
+// trackPresence sends the heartbeats of the players subscribed to this
+// server and marks the players no server has seen for the grace period as
+// disconnected, until done is closed. Presence lives in the store, so
+// a player who reconnects through another server stays connected.
+func (s *server) trackPresence(done <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+
+		for token, logins := range s.hub.present() {
+			if err := s.store.Lobby().Heartbeat(context.Background(), token, logins); err != nil {
+				s.logger.Errorf("unable to record presence in lobby %s: %v", token, err)
+			}
+		}
+
+		if err := s.store.Lobby().ExpirePresence(context.Background(), gracePeriod); err != nil {
+			s.logger.Errorf("unable to expire presence: %v", err)
+		}
+	}
+}
+
+// touch records that login, if they are a player of the lobby, is still
+// there because they made a request to it. Clients that play through the
+// REST API without a subscription would otherwise be shown as disconnected
+// once the grace period is over.
+func (s *server) touch(l *model.Lobby, login string) {
+	if !u.Contains(l.AllPlayers, login) {
+		return
+	}
+
+	if err := s.store.Lobby().Heartbeat(context.Background(), l.Token, []string{login}); err != nil {
+		s.logger.Errorf("unable to record presence of %s in lobby %s: %v", login, l.Token, err)
+	}
+
+	if !l.Online[login] {
+		s.setOnline(l.Token, login, true)
+	}
+}
+
+// setOnline func
+func (s *server) setOnline(token, login string, online bool) {
+	l, err := s.store.Lobby().FindByToken(context.Background(), token)
+	if err != nil {
+		s.logger.Errorf("unable to load lobby %s: %v", token, err)
+		return
+	}
+
+	if l.Online[login] == online {
+		return
+	}
+
+	if err := s.store.Lobby().SetOnline(context.Background(), l, login, online); err != nil {
+		s.logger.Errorf("unable to update presence of %s in lobby %s: %v", login, token, err)
+	}
+}
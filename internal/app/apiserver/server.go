@@ -1,11 +1,15 @@
 package apiserver
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -21,20 +25,43 @@ import (
 )
 
 type server struct {
-	router *mux.Router
-	logger *logrus.Logger
-	store  store.Store
+	router   *mux.Router
+	logger   *logrus.Logger
+	store    store.Store
+	hub      *hub
+	clock    *clock
+	joins    *throttle
+	codeSize int
 }
 
-func newServer(store store.Store) *server {
+func newServer(store store.Store, listener store.Listener, config *Config) *server {
+	logger := logrus.New()
+
+	proxies, err := parseProxies(config.TrustedProxies)
+	if err != nil {
+		logger.Errorf("ignoring trusted_proxies: %v", err)
+	}
+
 	s := &server{
-		router: mux.NewRouter(),
-		logger: logrus.New(),
-		store:  store,
+		router:   mux.NewRouter(),
+		logger:   logger,
+		store:    store,
+		hub:      newHub(listener, logger),
+		clock:    newClock(),
+		joins:    newThrottle(config.JoinAttempts, time.Duration(config.JoinAttemptWindow)*time.Second, proxies),
+		codeSize: config.InviteCodeLength,
 	}
 
 	s.configureRouter()
 
+	done := make(chan struct{})
+	go func() {
+		s.dispatch(listener.Notifications())
+		close(done)
+	}()
+	go s.sweepRounds(done)
+	go s.trackPresence(done)
+
 	return s
 }
 
@@ -47,14 +74,51 @@ func (s *server) configureRouter() {
 
 	s.router.Use(jwt.JwtAuthentication)
 	s.router.Use(s.logRequest)
+	s.router.Use(s.throttleTokens)
 	s.router.HandleFunc("/user/new", s.createUser()).Methods("POST")
 	s.router.HandleFunc("/user/login", s.logUser()).Methods("POST")
 	s.router.HandleFunc("/lobby/create", s.createLobby()).Methods("POST")
-	s.router.HandleFunc("/lobby/adminconnect/{token}", s.connectLobby()).Methods("POST")
-	s.router.HandleFunc("/lobby/connect/{token}", s.connectLobby()).Methods("POST")
+	s.router.HandleFunc("/lobbies", s.listLobbies()).Methods("GET")
+	s.router.HandleFunc("/lobby/quickmatch", s.quickMatch()).Methods("POST")
+	s.router.HandleFunc("/lobby/adminconnect/{token}", s.connectLobby(true)).Methods("POST")
+	s.router.HandleFunc("/lobby/connect/{token}", s.connectLobby(false)).Methods("POST")
+	s.router.HandleFunc("/lobby/leave/{token}", s.leaveLobby()).Methods("POST")
+	s.router.HandleFunc("/lobby/watch/{token}", s.watchLobby()).Methods("POST")
+	s.router.HandleFunc("/lobby/unwatch/{token}", s.stopWatching()).Methods("POST")
+	s.router.HandleFunc("/lobby/kick/{token}", s.kickPlayer()).Methods("POST")
+	s.router.HandleFunc("/lobby/unban/{token}", s.unbanPlayer()).Methods("POST")
+	s.router.HandleFunc("/lobby/settings/{token}", s.updateSettings()).Methods("PUT")
+	s.router.HandleFunc("/lobby/host/{token}", s.transferHost()).Methods("POST")
+	s.router.HandleFunc("/lobby/end/{token}", s.endGame()).Methods("POST")
+	s.router.HandleFunc("/lobby/ready/{token}", s.setReady()).Methods("POST")
 	s.router.HandleFunc("/lobby/start/{token}", s.startGame()).Methods("POST")
+	s.router.HandleFunc("/lobby/nextround/{token}", s.nextRound()).Methods("POST")
+	s.router.HandleFunc("/lobby/scoreboard/{token}", s.scoreboard()).Methods("GET")
+	s.router.HandleFunc("/lobby/history/{token}", s.history()).Methods("GET")
 	s.router.HandleFunc("/lobby/checkresult/{token}", s.checkResult()).Methods("GET")
 	s.router.HandleFunc("/lobby/checklocation/{token}", s.checkLocation()).Methods("POST")
+	s.router.HandleFunc("/lobby/accuse/{token}", s.accuse()).Methods("POST")
+	s.router.HandleFunc("/lobby/vote/{token}", s.vote()).Methods("POST")
+	s.router.HandleFunc("/lobby/pause/{token}", s.pauseTimer()).Methods("POST")
+	s.router.HandleFunc("/lobby/resume/{token}", s.resumeTimer()).Methods("POST")
+	s.router.HandleFunc("/lobby/ws/{token}", s.lobbySocket()).Methods("GET")
+	s.router.HandleFunc("/pack/create", s.createPack()).Methods("POST")
+	s.router.HandleFunc("/packs", s.listPacks()).Methods("GET")
+	s.router.HandleFunc("/pack/{id:[0-9]+}", s.getPack()).Methods("GET")
+	s.router.HandleFunc("/pack/{id:[0-9]+}", s.updatePack()).Methods("PUT")
+	s.router.HandleFunc("/pack/{id:[0-9]+}", s.deletePack()).Methods("DELETE")
+	s.router.HandleFunc("/lobby/events/{token}", s.lobbyEvents()).Methods("GET")
+}
+
+// redactedURI returns the request URI with the auth token hidden
+func redactedURI(r *http.Request) string {
+	query := r.URL.Query()
+	if query.Get(jwt.QueryParam) == "" {
+		return r.RequestURI
+	}
+
+	query.Set(jwt.QueryParam, "REDACTED")
+	return r.URL.Path + "?" + query.Encode()
 }
 
 func (s *server) logRequest(next http.Handler) http.Handler {
@@ -64,7 +128,7 @@ func (s *server) logRequest(next http.Handler) http.Handler {
 			"remote_addr": r.RemoteAddr,
 		})
 
-		logger.Infof("started %s %s", r.Method, r.RequestURI)
+		logger.Infof("started %s %s", r.Method, redactedURI(r))
 		start := time.Now()
 		rw := &responseWriter{w, http.StatusOK}
 		next.ServeHTTP(rw, r)
@@ -111,20 +175,20 @@ func (s *server) createUser() http.HandlerFunc {
 			Password: req.Password,
 		}
 
-		checkUser, err := s.store.User().FindByLogin(req.Login)
+		checkUser, err := s.store.User().FindByLogin(r.Context(), req.Login)
 		if checkUser != nil {
 			response := u.Message(false, "This username is already used. Please try another username or reauthorize")
 			u.Respond(w, response)
 			return
 		}
 
-		if err := s.store.User().Create(usermodel); err != nil {
+		if err := s.store.User().Create(r.Context(), usermodel); err != nil {
 			response := u.Message(false, "Error while creating user")
 			u.Respond(w, response)
 			return
 		}
 
-		locUser, err := s.store.User().FindByLogin(req.Login)
+		locUser, err := s.store.User().FindByLogin(r.Context(), req.Login)
 		if err != nil {
 			u.Error(w, http.StatusUnprocessableEntity, err)
 			return
@@ -169,7 +233,7 @@ func (s *server) logUser() http.HandlerFunc {
 			Password: req.Password,
 		}
 
-		loc, err := s.store.User().FindByLogin(req.Login)
+		loc, err := s.store.User().FindByLogin(r.Context(), req.Login)
 		if err != nil && loc == nil {
 			u.Error(w, http.StatusUnauthorized, err)
 			return
@@ -198,12 +262,21 @@ func (s *server) logUser() http.HandlerFunc {
 func (s *server) createLobby() http.HandlerFunc {
 
 	type request struct {
-		AmountPl  int `json:"amountpl"`
-		AmountSpy int `json:"amountspy"`
+		lobbySettings
+		BoardSize int    `json:"boardsize"`
+		Packs     []int  `json:"packs"`
+		Public    bool   `json:"public"`
+		Password  string `json:"password"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
+		user, err := s.currentUser(r)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
 		req := &request{}
 
 		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
@@ -211,106 +284,201 @@ func (s *server) createLobby() http.HandlerFunc {
 			return
 		}
 
-		lobbymodel := &model.Lobby{
-			AmountPl:  req.AmountPl,
-			AmountSpy: req.AmountSpy,
+		if message := req.normalize(); message != "" {
+			response := u.Message(false, message)
+			u.Respond(w, response)
+			return
 		}
 
-		token := u.TokenGenerator()
-		for lobbymodel, err := s.store.Lobby().FindByToken(token); lobbymodel != nil && err == nil; {
-			token = u.TokenGenerator()
+		if req.BoardSize == 0 {
+			req.BoardSize = defaultBoardSize
 		}
 
-		lobbymodel.Token = token
-
-		lobbymodel.Locations, lobbymodel.CurrentLocation = LocationsGenerator()
+		if req.BoardSize < minBoardSize || req.BoardSize > maxBoardSize {
+			response := u.Message(false, fmt.Sprintf("Board size must be between %d and %d locations", minBoardSize, maxBoardSize))
+			u.Respond(w, response)
+			return
+		}
 
-		lobbymodel.Status = "Created"
+		catalogue := model.DefaultLocations
+		if len(req.Packs) > 0 {
+			packs := make([][]model.Location, 0, len(req.Packs))
+			for _, id := range req.Packs {
+				pack, err := s.store.Pack().Find(r.Context(), id)
+				if err != nil {
+					u.Error(w, http.StatusUnprocessableEntity, fmt.Errorf("Pack %d: %w", id, err))
+					return
+				}
+				packs = append(packs, pack.Locations)
+			}
+			catalogue = model.MergeLocations(packs...)
+		}
 
-		if err := s.store.Lobby().Create(lobbymodel); err != nil {
-			u.Error(w, http.StatusUnprocessableEntity, err)
+		if len(catalogue) < req.BoardSize {
+			response := u.Message(false, fmt.Sprintf("The chosen packs have %d locations, a board of %d needs more", len(catalogue), req.BoardSize))
+			u.Respond(w, response)
 			return
 		}
 
-		currentlobby, err := s.store.Lobby().FindByToken(lobbymodel.Token)
+		lobbymodel := &model.Lobby{
+			Host:          user.Login,
+			MinPl:         req.MinPl,
+			AmountPl:      req.AmountPl,
+			AmountSpy:     req.AmountSpy,
+			RoundDuration: req.RoundDuration,
+			Rounds:        req.Rounds,
+			Public:        req.Public,
+			Password:      req.Password,
+		}
+
+		if req.Password != "" {
+			if resp, ok := lobbymodel.EncryptPassword(); !ok {
+				u.Respond(w, resp)
+				return
+			}
+		}
+
+		var currentlobby *model.Lobby
+		err = s.store.WithTx(r.Context(), func(tx store.Store) error {
+			if err := s.openLobby(r.Context(), tx, lobbymodel, catalogue, req.BoardSize); err != nil {
+				return err
+			}
+
+			currentlobby, err = tx.Lobby().FindByToken(r.Context(), lobbymodel.Token)
+			return err
+		})
 		if err != nil {
 			u.Error(w, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		currentlobby.CurrentLocation = ""
-
 		response := u.Message(true, "Lobby has been created")
-		response["lobby"] = currentlobby
-		u.Respond(w, response)
+		response["lobby"] = currentlobby.ViewFor("")
+		u.Respond(w, withClock(response, currentlobby))
 	}
 }
 
-// connectLobby func
-func (s *server) connectLobby() http.HandlerFunc {
+// openLobby draws the board of a new lobby from the catalogue and saves it
+// under a fresh token in st
+func (s *server) openLobby(ctx context.Context, st store.Store, l *model.Lobby, catalogue []model.Location, boardSize int) error {
+	size := s.codeSize
+	if size < minInviteCodeLength {
+		size = minInviteCodeLength
+	}
+
+	for {
+		l.Token = u.TokenGenerator(size)
+
+		_, err := st.Lobby().FindByToken(ctx, l.Token)
+		if err == store.ErrRecordNotFound {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	board, _ := LocationsGenerator(catalogue, boardSize)
+
+	l.LocationRoles = make(map[string][]string, len(board))
+	for _, location := range board {
+		l.Locations = append(l.Locations, location.Name)
+		l.LocationRoles[location.Name] = location.Roles
+	}
+
+	l.Status = model.StatusCreated
+
+	return st.Lobby().Create(ctx, l)
+}
+
+// connectLobby joins the authenticated user to the lobby. The admin variant
+// is reserved for the host.
+func (s *server) connectLobby(admin bool) http.HandlerFunc {
 
 	type request struct {
-		Login string `json:"login"`
+		Password string `json:"password"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		req := &request{}
-
 		vars := mux.Vars(r)
 		token := vars["token"]
 
-		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
 			u.Error(w, http.StatusBadRequest, err)
 			return
 		}
 
-		currentlobby, err := s.store.Lobby().FindByToken(token)
+		// The player joins with the lobby locked, so that the capacity and the
+		// status it is joined in can't change in between
+		var sub *subscriber
+		rejoined := false
+		user, _, err := s.withLobby(r, token, func(user *model.User, l *model.Lobby) error {
+			if err := admit(req.Password)(user, l); err != nil {
+				return err
+			}
+
+			if admin && !l.IsHost(user.Login) {
+				return errNotHost
+			}
+
+			rejoined = u.Contains(l.AllPlayers, user.Login)
+			if !rejoined && !l.Status.Joinable() {
+				return refusal("Game has started already, you can't enter")
+			}
+
+			return nil
+		}, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			// The subscription is taken before the join is committed, so that
+			// no change made after it is missed while waiting for the game
+			sub, _, _ = s.hub.subscribe(token, user.Login, 0)
+
+			if rejoined {
+				return nil
+			}
+
+			return tx.Lobby().ConnectUserToLobby(r.Context(), l, user.Login)
+		})
+		if sub != nil {
+			defer s.unsubscribe(token, sub)
+		}
 		if err != nil {
-			u.Error(w, http.StatusUnprocessableEntity, err)
+			s.failWrongPassword(r, err)
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		if status, err := s.store.Lobby().CheckStatus(token); status == "Started" && err == nil {
-			response := u.Message(false, "Game has started already, you can't enter")
-			u.Respond(w, response)
+		if err := s.awaitStatus(r.Context(), token, user.Login, sub, model.StatusInProgress, model.StatusVoting, model.StatusFinished); err != nil {
+			if r.Context().Err() == nil {
+				respondError(w, http.StatusUnprocessableEntity, err)
+			}
 			return
 		}
 
-		currentlobby.AllPlayers = append(currentlobby.AllPlayers, req.Login)
-
-		err = s.store.Lobby().ConnectUserToLobby(currentlobby)
+		connectedlobby, err := s.store.Lobby().FindByToken(r.Context(), token)
 		if err != nil {
-			u.Error(w, http.StatusUnprocessableEntity, err)
+			respondError(w, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		for {
-			if status, err := s.store.Lobby().CheckStatus(token); status == "Started" && err == nil {
+		view := connectedlobby.ViewFor(user.Login)
 
-				connectedlobby, err := s.store.Lobby().FindByToken(token)
-				if err != nil {
-					u.Error(w, http.StatusUnprocessableEntity, err)
-					return
-				}
-
-				if flag := u.Contains(connectedlobby.SpyPlayers, req.Login); flag == true {
-					var cleararray []string
-					connectedlobby.CurrentLocation = ""
-					connectedlobby.SpyPlayers = cleararray
-					response := u.Message(true, "Game has started, you are spy")
-					response["lobby"] = connectedlobby
-					u.Respond(w, response)
-				} else {
-					var cleararray []string
-					connectedlobby.SpyPlayers = cleararray
-					response := u.Message(true, "Game has started, you are peaceful")
-					response["lobby"] = connectedlobby
-					u.Respond(w, response)
-				}
-				break
-			}
+		message := connectedlobby.Result()
+		switch view.Viewer {
+		case model.ViewerSpy:
+			message = "Game has started, you are spy"
+		case model.ViewerPeaceful:
+			message = "Game has started, you are peaceful"
 		}
+
+		response := u.Message(true, message)
+		response["rejoined"] = rejoined
+		response["role"] = view.Role
+		response["scoreboard"] = connectedlobby.Scoreboard()
+		response["lobby"] = view
+		u.Respond(w, withClock(response, connectedlobby))
 	}
 }
 
@@ -318,7 +486,6 @@ func (s *server) connectLobby() http.HandlerFunc {
 func (s *server) checkLocation() http.HandlerFunc {
 
 	type checkrequest struct {
-		Login    string `json:"login"`
 		Location string `json:"location"`
 	}
 
@@ -334,147 +501,800 @@ func (s *server) checkLocation() http.HandlerFunc {
 			return
 		}
 
-		connectedlobby, err := s.store.Lobby().FindByToken(token)
-		if err != nil {
-			u.Error(w, http.StatusUnprocessableEntity, err)
-			return
-		}
+		// The guess is judged and the round ended with the lobby locked, so
+		// that the verdict and points are never computed from a stale round
+		var result string
+		user, connectedlobby, err := s.withLobby(r, token, checkPlayer, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			if !u.Contains(l.SpyPlayers, user.Login) {
+				return refusal("Misha, a ti krasava")
+			}
 
-		if flag := u.Contains(connectedlobby.SpyPlayers, cheklocreq.Login); flag == true {
-			if connectedlobby.CurrentLocation == cheklocreq.Location {
-				result, err := s.store.Lobby().WonForSpy(connectedlobby)
-				if err != nil {
-					response := u.Message(false, "Unavailiable to end game for spy")
-					u.Respond(w, response)
-					return
+			if l.Status != model.StatusInProgress {
+				return fmt.Errorf("%w: the spy can only guess while the round is in progress", model.ErrIllegalTransition)
+			}
+
+			var err error
+			if l.CurrentLocation == cheklocreq.Location {
+				result, err = tx.Lobby().WonForSpy(r.Context(), l, model.OutcomeSpyGuessed)
+				if err != nil && !errors.Is(err, model.ErrIllegalTransition) {
+					return refusal("Unavailiable to end game for spy")
 				}
-				response := u.Message(true, result)
-				response["lobby"] = connectedlobby
-				u.Respond(w, response)
 			} else {
-				result, err := s.store.Lobby().WonForPeaceful(connectedlobby)
-				if err != nil {
-					response := u.Message(false, "Unavailiable to end game for peaceful")
-					u.Respond(w, response)
-					return
+				result, err = tx.Lobby().WonForPeaceful(r.Context(), l, model.OutcomeSpyMissed)
+				if err != nil && !errors.Is(err, model.ErrIllegalTransition) {
+					return refusal("Unavailiable to end game for peaceful")
 				}
-				response := u.Message(true, result)
-				response["lobby"] = connectedlobby
-				u.Respond(w, response)
 			}
-		} else {
-			response := u.Message(false, "Misha, a ti krasava")
-			u.Respond(w, response)
+			return err
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
 		}
-		return
+
+		response := u.Message(true, result)
+		response["lobby"] = connectedlobby.ViewFor(user.Login)
+		u.Respond(w, withClock(response, connectedlobby))
 	}
 }
 
-func (s *server) checkResult() http.HandlerFunc {
+// accuse func
+func (s *server) accuse() http.HandlerFunc {
+
+	type request struct {
+		Suspect string `json:"suspect"`
+	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		vars := mux.Vars(r)
 		token := vars["token"]
 
-		for {
-			if status, err := s.store.Lobby().CheckStatus(token); status == "Spy won" && err == nil {
+		req := &request{}
 
-				connectedlobby, err := s.store.Lobby().FindByToken(token)
-				if err != nil {
-					u.Error(w, http.StatusUnprocessableEntity, err)
-					return
-				}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
 
-				response := u.Message(true, status)
-				response["lobby"] = connectedlobby
-				u.Respond(w, response)
+		_, currentlobby, err := s.withLobby(r, token, checkPlayer, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			if !u.Contains(l.AllPlayers, req.Suspect) {
+				return refusal("The suspect must be a player of this lobby")
+			}
 
-				break
-			} else if status, err := s.store.Lobby().CheckStatus(token); status == "Peaceful won" && err == nil {
+			if user.Login == req.Suspect {
+				return refusal("You can't accuse yourself")
+			}
 
-				connectedlobby, err := s.store.Lobby().FindByToken(token)
-				if err != nil {
-					u.Error(w, http.StatusUnprocessableEntity, err)
-					return
-				}
+			return tx.Lobby().Accuse(r.Context(), l, user.Login, req.Suspect)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.scheduleRound(currentlobby)
+
+		response := u.Message(true, "Accusation has been made, waiting for votes")
+		response["suspect"] = currentlobby.Suspect
+		response["votes"] = currentlobby.Votes
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// vote func
+func (s *server) vote() http.HandlerFunc {
 
-				response := u.Message(true, status)
-				response["lobby"] = connectedlobby
-				u.Respond(w, response)
+	type request struct {
+		Vote bool `json:"vote"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
 
-				break
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			u.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// The vote and the verdict it brings are written in one unit of work,
+		// with the lobby locked so that the last two votes can't both miss it
+		var result string
+		var decided, dismissed bool
+		user, currentlobby, err := s.withLobby(r, token, checkPlayer, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			if !u.Contains(l.Voters(), user.Login) {
+				return refusal("You can't vote on this accusation")
+			}
+
+			err := tx.Lobby().Vote(r.Context(), l, user.Login, req.Vote)
+			if err != nil {
+				return err
 			}
+
+			var convicted bool
+			decided, convicted = l.Verdict()
+			switch {
+			case !decided:
+				return nil
+			case !convicted && !l.TimeUp():
+				dismissed = true
+				return tx.Lobby().DismissAccusation(r.Context(), l)
+			case !convicted:
+				result, err = tx.Lobby().WonForSpy(r.Context(), l, model.OutcomeSpyNotIndicted)
+			case u.Contains(l.SpyPlayers, l.Suspect):
+				result, err = tx.Lobby().WonForPeaceful(r.Context(), l, model.OutcomeSpyCaught)
+			default:
+				result, err = tx.Lobby().WonForSpy(r.Context(), l, model.OutcomeWrongConviction)
+			}
+			return err
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if !decided {
+			response := u.Message(true, "Vote has been counted")
+			response["votes"] = currentlobby.Votes
+			u.Respond(w, withClock(response, currentlobby))
+			return
 		}
+
+		if dismissed {
+			s.scheduleRound(currentlobby)
+
+			response := u.Message(true, "Accusation has been rejected, the game goes on")
+			u.Respond(w, withClock(response, currentlobby))
+			return
+		}
+
+		response := u.Message(true, result)
+		response["lobby"] = currentlobby.ViewFor(user.Login)
+		u.Respond(w, withClock(response, currentlobby))
 	}
 }
 
-// startGame func
-func (s *server) startGame() http.HandlerFunc {
+func (s *server) checkResult() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		vars := mux.Vars(r)
 		token := vars["token"]
 
-		currentlobby, err := s.store.Lobby().FindByToken(token)
+		user, err := s.currentUser(r)
 		if err != nil {
-			u.Error(w, http.StatusUnprocessableEntity, err)
+			respondError(w, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		playersarray := currentlobby.AllPlayers
+		sub, _, _ := s.subscribe(token, user.Login, 0)
+		defer s.unsubscribe(token, sub)
 
-		if len(playersarray) != currentlobby.AmountPl {
-			response := u.Message(false, "Unable to start game, not enough players")
-			u.Respond(w, response)
+		if err := s.awaitStatus(r.Context(), token, user.Login, sub, model.StatusFinished); err != nil {
+			if r.Context().Err() == nil {
+				respondError(w, http.StatusUnprocessableEntity, err)
+			}
+			return
+		}
+
+		connectedlobby, err := s.store.Lobby().FindByToken(r.Context(), token)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		var spyplayers []string = make([]string, 0, 20)
-		j := currentlobby.AmountPl
+		response := u.Message(true, connectedlobby.Result())
+		response["lobby"] = connectedlobby.ViewFor(user.Login)
+		u.Respond(w, withClock(response, connectedlobby))
+	}
+}
+
+// startGame starts the match once every player is ready, or right away when
+// the host forces it
+func (s *server) startGame() http.HandlerFunc {
+
+	type request struct {
+		Force bool `json:"force"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
 
-		for i := currentlobby.AmountSpy; i > 0; i-- {
-			a := rand.Intn(j)
-			spyplayers = append(spyplayers, playersarray[a])
-			playersarray = append(playersarray[:a], playersarray[a+1:]...)
-			j--
+		req := &request{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+			u.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
-		currentlobby.SpyPlayers = spyplayers
+		s.beginRound(w, r, token, func(l *model.Lobby) error {
+			if l.Status == model.StatusFinished {
+				return fmt.Errorf("%w: the game has started already", model.ErrIllegalTransition)
+			}
+
+			if err := l.CheckTransition(model.StatusInProgress); err != nil {
+				return err
+			}
+
+			if err := l.CheckStart(); err != nil {
+				return err
+			}
+
+			if unready := l.Unready(); len(unready) > 0 && !req.Force {
+				return fmt.Errorf("%w: waiting for %s", model.ErrNotReady, strings.Join(unready, ", "))
+			}
+
+			return nil
+		}, "Game has started")
+	}
+}
+
+// nextRound func
+func (s *server) nextRound() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		s.beginRound(w, r, token, func(l *model.Lobby) error {
+			if l.Status != model.StatusFinished {
+				return fmt.Errorf("%w: the current round is not over", model.ErrIllegalTransition)
+			}
+
+			if err := l.CheckTransition(model.StatusInProgress); err != nil {
+				return err
+			}
 
-		err = s.store.Lobby().ChooseSpyPlayersInLobby(currentlobby)
+			return l.CheckStart()
+		}, "")
+	}
+}
+
+// beginRound deals and starts the next round of the lobby if the authenticated
+// user is its host and check passes, and responds with message, by default
+// the number of the round. The lobby is locked from the checks to the start
+// so that a round is never dealt twice.
+func (s *server) beginRound(w http.ResponseWriter, r *http.Request, token string, check func(*model.Lobby) error, message string) {
+
+	user, err := s.currentUser(r)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var currentlobby *model.Lobby
+	var checked bool
+	err = s.store.WithTx(r.Context(), func(tx store.Store) error {
+		l, err := tx.Lobby().FindForUpdate(r.Context(), token)
+		if err != nil {
+			return err
+		}
+		currentlobby = l
+
+		if err := checkHost(user, l); err != nil {
+			return err
+		}
+
+		if err := check(l); err != nil {
+			return err
+		}
+		checked = true
+
+		dealRound(l)
+
+		return tx.Lobby().StartGame(r.Context(), l)
+	})
+	if err != nil && (!checked || errors.Is(err, model.ErrIllegalTransition)) {
+		respondError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err != nil {
+		response := u.Message(false, "Unable to start game")
+		u.Respond(w, response)
+		return
+	}
+
+	s.scheduleRound(currentlobby)
+	s.touch(currentlobby, user.Login)
+
+	if message == "" {
+		message = fmt.Sprintf("Round %d has started", currentlobby.Round)
+	}
+
+	response := u.Message(true, message)
+	response["round"] = currentlobby.Round
+	response["dealer"] = currentlobby.Dealer
+	u.Respond(w, withClock(response, currentlobby))
+}
+
+// scoreboard func
+func (s *server) scoreboard() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		currentlobby, err := s.store.Lobby().FindByToken(r.Context(), token)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if _, err := s.lobbyMember(r, currentlobby); err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		response := u.Message(true, "Scoreboard")
+		response["round"] = currentlobby.Round
+		response["rounds"] = currentlobby.Rounds
+		response["matchover"] = currentlobby.MatchOver()
+		response["scoreboard"] = currentlobby.Scoreboard()
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// history lists the finished rounds of the match with their spies, roles and
+// points to the players and spectators of the lobby
+func (s *server) history() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		currentlobby, err := s.store.Lobby().FindByToken(r.Context(), token)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if _, err := s.lobbyMember(r, currentlobby); err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		rounds, err := s.store.Lobby().History(r.Context(), token)
 		if err != nil {
 			u.Error(w, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		err = s.store.Lobby().StartGame(currentlobby)
+		response := u.Message(true, "History")
+		response["rounds"] = rounds
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// pauseTimer func
+func (s *server) pauseTimer() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		_, currentlobby, err := s.withLobby(r, token, checkHost, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			return tx.Lobby().PauseTimer(r.Context(), l)
+		})
 		if err != nil {
-			response := u.Message(false, "Unable to start game")
-			u.Respond(w, response)
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		response := u.Message(true, "Game has started")
-		u.Respond(w, response)
+		s.scheduleRound(currentlobby)
+
+		response := u.Message(true, "Game has been paused")
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// resumeTimer func
+func (s *server) resumeTimer() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		_, currentlobby, err := s.withLobby(r, token, checkHost, func(tx store.Store, user *model.User, l *model.Lobby) error {
+			return tx.Lobby().ResumeTimer(r.Context(), l)
+		})
+		if err != nil {
+			respondLobbyError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.scheduleRound(currentlobby)
+
+		response := u.Message(true, "Game has been resumed")
+		u.Respond(w, withClock(response, currentlobby))
+	}
+}
+
+// withClock adds the round clock of the lobby to a response or an event, as
+// every lobby response and event carries the time left in the round
+func withClock(data map[string]interface{}, l *model.Lobby) map[string]interface{} {
+	clock := l.Clock()
+	data["timeleft"] = clock.TimeLeft
+	data["deadline"] = clock.Deadline
+	data["paused"] = clock.Paused
+	return data
+}
+
+// currentUser returns the user authenticated by jwt.JwtAuthentication
+func (s *server) currentUser(r *http.Request) (*model.User, error) {
+	id, ok := r.Context().Value("user").(uint)
+	if !ok {
+		return nil, errNotAuthenticated
+	}
+
+	user, err := s.store.User().Find(r.Context(), int(id))
+	if err == store.ErrRecordNotFound {
+		return nil, errNotAuthenticated
+	}
+
+	return user, err
+}
+
+// withLobby runs fn in a unit of work on the lobby, locked against concurrent
+// changes, once check passes for the authenticated user. It returns the user
+// and the lobby as fn left it, and counts the request as a sign of the user's
+// presence.
+func (s *server) withLobby(r *http.Request, token string, check func(*model.User, *model.Lobby) error, fn func(tx store.Store, user *model.User, l *model.Lobby) error) (*model.User, *model.Lobby, error) {
+	user, err := s.currentUser(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var locked *model.Lobby
+	err = s.store.WithTx(r.Context(), func(tx store.Store) error {
+		l, err := tx.Lobby().FindForUpdate(r.Context(), token)
+		if err != nil {
+			return err
+		}
+		locked = l
+
+		if err := check(user, l); err != nil {
+			return err
+		}
+
+		return fn(tx, user, l)
+	})
+
+	if err == nil {
+		s.touch(locked, user.Login)
+	}
+
+	return user, locked, err
+}
+
+// checkPlayer returns errNotMember unless user is a player of the lobby
+func checkPlayer(user *model.User, l *model.Lobby) error {
+	if !u.Contains(l.AllPlayers, user.Login) {
+		return errNotMember
+	}
+
+	return nil
+}
+
+// checkSpectator returns errNotMember unless user is a spectator of the lobby
+func checkSpectator(user *model.User, l *model.Lobby) error {
+	if !u.Contains(l.Spectators, user.Login) {
+		return errNotMember
+	}
+
+	return nil
+}
+
+// throttleTokens throttles the clients of lobby endpoints, counting the
+// requests answered with 404 as failed attempts. Lobby handlers answer so for
+// a lobby that doesn't exist, so that lobby tokens can't be guessed through
+// any of them.
+func (s *server) throttleTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if _, ok := mux.Vars(r)["token"]; !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		addr := s.joins.clientAddr(r)
+		if !s.joins.allow(addr) {
+			respondError(w, http.StatusTooManyRequests, errTooManyAttempts)
+			return
+		}
+
+		rw := &responseWriter{w, http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		if rw.code == http.StatusNotFound {
+			s.joins.fail(addr)
+		}
+	})
+}
+
+// admit returns the check letting user into the lobby with password: its
+// members and host come back in, anyone else needs the lobby password
+func admit(password string) func(*model.User, *model.Lobby) error {
+	return func(user *model.User, l *model.Lobby) error {
+		if u.Contains(l.AllPlayers, user.Login) || u.Contains(l.Spectators, user.Login) || l.IsHost(user.Login) {
+			return nil
+		}
+
+		if !l.ComparePassword(password) {
+			return errWrongPassword
+		}
+
+		return nil
+	}
+}
+
+// failWrongPassword counts a wrong lobby password as a failed attempt of the
+// client, which throttleTokens throttles, so that passwords can't be brute
+// forced
+func (s *server) failWrongPassword(r *http.Request, err error) {
+	if errors.Is(err, errWrongPassword) {
+		s.joins.fail(s.joins.clientAddr(r))
+	}
+}
+
+// lobbyMember returns the authenticated user if they are a player or
+// a spectator of the lobby, counting the request as a sign of their presence
+func (s *server) lobbyMember(r *http.Request, l *model.Lobby) (*model.User, error) {
+	user, err := s.currentUser(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.Contains(l.AllPlayers, user.Login) && !u.Contains(l.Spectators, user.Login) {
+		return nil, errNotMember
+	}
+
+	s.touch(l, user.Login)
+
+	return user, nil
+}
+
+// checkHost returns errNotHost unless user is the host of the lobby
+func checkHost(user *model.User, l *model.Lobby) error {
+	if !l.IsHost(user.Login) {
+		return errNotHost
+	}
+
+	return nil
+}
+
+// awaitStatus blocks until the lobby has one of the statuses, checking it
+// again on every event the subscriber receives. It fails with errLobbyClosed
+// once the lobby is abandoned, with errNotMember once login has left or been
+// kicked, and with the context error if the request is cancelled first.
+func (s *server) awaitStatus(ctx context.Context, token, login string, sub *subscriber, statuses ...model.LobbyStatus) error {
+	for {
+		status, err := s.store.Lobby().CheckStatus(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		for _, expected := range statuses {
+			if status == expected {
+				return nil
+			}
+		}
+
+		if status == model.StatusAbandoned {
+			return errLobbyClosed
+		}
+
+		select {
+		case e := <-sub.events:
+			switch e.Type {
+			case eventLobbyClosed:
+				return errLobbyClosed
+			case eventPlayerLeft, eventPlayerKicked:
+				if data, ok := e.Data.(map[string]interface{}); ok && data["login"] == login {
+					return errNotMember
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// dispatch turns lobby change notifications into events for the local subscribers
+func (s *server) dispatch(notifications <-chan *store.Notification) {
+	for n := range notifications {
+		l, err := s.store.Lobby().FindByToken(context.Background(), n.Token)
+		if err != nil {
+			s.logger.Errorf("unable to load lobby %s: %v", n.Token, err)
+			continue
+		}
+
+		s.scheduleRound(l)
+
+		switch n.Event {
+		case store.LobbyPlayerJoined:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventPlayerJoined,
+				Data: withClock(map[string]interface{}{"login": n.Login, "players": l.AllPlayers, "lobby": l.ViewFor("")}, l),
+			})
+		case store.LobbyGameStarted:
+			s.publishGameStarted(l, n.Seq)
+		case store.LobbyGameEnded:
+			s.publishGameEnded(l, n.Seq)
+		case store.LobbyAccusation:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventAccusation,
+				Data: withClock(map[string]interface{}{"accuser": l.Accuser, "suspect": l.Suspect}, l),
+			})
+		case store.LobbyVoteCast:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventVote,
+				Data: withClock(map[string]interface{}{"login": n.Login, "vote": l.Votes[n.Login]}, l),
+			})
+		case store.LobbyAcquittal:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventAcquittal,
+				Data: withClock(map[string]interface{}{"suspect": n.Login}, l),
+			})
+		case store.LobbyClockStopped, store.LobbyClockStarted:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventClock,
+				Data: withClock(map[string]interface{}{}, l),
+			})
+		case store.LobbyTimeUp:
+			s.hub.publish(l.Token, n.Seq, &event{Type: eventTimeUp, Data: withClock(map[string]interface{}{}, l)})
+		case store.LobbyPlayerLeft:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventPlayerLeft,
+				Data: withClock(map[string]interface{}{"login": n.Login, "players": l.AllPlayers, "lobby": l.ViewFor("")}, l),
+			})
+		case store.LobbyPlayerKicked:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventPlayerKicked,
+				Data: withClock(map[string]interface{}{"login": n.Login, "players": l.AllPlayers, "banned": l.Banned, "lobby": l.ViewFor("")}, l),
+			})
+		case store.LobbyUnbanned:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventUnbanned,
+				Data: withClock(map[string]interface{}{"login": n.Login, "banned": l.Banned}, l),
+			})
+		case store.LobbyReady:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventReady,
+				Data: withClock(map[string]interface{}{"login": n.Login, "ready": l.Ready[n.Login], "unready": l.Unready()}, l),
+			})
+		case store.LobbyPresence:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventPresence,
+				Data: withClock(map[string]interface{}{"login": n.Login, "online": l.Online[n.Login], "unready": l.Unready()}, l),
+			})
+		case store.LobbyWatching, store.LobbyUnwatched:
+			eventType := eventWatching
+			if n.Event == store.LobbyUnwatched {
+				eventType = eventUnwatched
+			}
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventType,
+				Data: withClock(map[string]interface{}{"login": n.Login, "spectators": l.Spectators}, l),
+			})
+		case store.LobbyHostChanged:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventHostChanged,
+				Data: withClock(map[string]interface{}{"host": l.Host}, l),
+			})
+		case store.LobbySettings:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventSettings,
+				Data: withClock(map[string]interface{}{"minpl": l.MinPl, "amountpl": l.AmountPl, "amountspy": l.AmountSpy, "roundduration": l.RoundDuration, "rounds": l.Rounds}, l),
+			})
+		case store.LobbyClosed:
+			s.hub.publish(l.Token, n.Seq, &event{
+				Type: eventLobbyClosed,
+				Data: withClock(map[string]interface{}{"status": l.Status, "scoreboard": l.Scoreboard()}, l),
+			})
+		}
+	}
+}
+
+// publishGameStarted sends everyone the public state of the new round and
+// every player their own view of it
+func (s *server) publishGameStarted(l *model.Lobby, seq int64) {
+	public := l.ViewFor("")
+	s.hub.publish(l.Token, seq, &event{
+		Type: eventGameStarted,
+		Data: withClock(map[string]interface{}{"players": public.AllPlayers, "locations": public.Locations, "round": public.Round, "rounds": public.Rounds, "dealer": public.Dealer, "lobby": public}, l),
+	})
+
+	for _, login := range l.AllPlayers {
+		view := l.ViewFor(login)
+		s.hub.publishTo(l.Token, seq, login, &event{
+			Type: eventRole,
+			Data: withClock(map[string]interface{}{"spy": view.Viewer == model.ViewerSpy, "location": view.CurrentLocation, "role": view.Role, "lobby": view}, l),
+		})
+	}
+}
+
+// publishGameEnded sends everyone the revealed round
+func (s *server) publishGameEnded(l *model.Lobby, seq int64) {
+	view := l.ViewFor("")
+	s.hub.publish(l.Token, seq, &event{
+		Type: eventGameEnded,
+		Data: withClock(map[string]interface{}{"status": view.Status, "winner": view.Winner, "outcome": view.Outcome, "location": view.CurrentLocation, "spyplayers": view.SpyPlayers, "suspect": view.Suspect, "scoreboard": l.Scoreboard(), "matchover": l.MatchOver(), "lobby": view}, l),
+	})
+}
+
+// Round durations in seconds
+const (
+	defaultRoundDuration = 8 * 60
+	minRoundDuration     = 60
+	maxRoundDuration     = 60 * 60
+)
+
+// minInviteCodeLength is the shortest lobby token handed out
+const minInviteCodeLength = 6
+
+// defaultMaxPlayers is the capacity of a lobby created without one
+const defaultMaxPlayers = 8
+
+// maxRounds is the largest number of rounds in a match
+const maxRounds = 20
+
+// Number of locations on a lobby board
+const (
+	defaultBoardSize = 12
+	minBoardSize     = 4
+	maxBoardSize     = 40
+)
+
+// dealRound chooses the dealer, location, spies and roles of the lobby's next round.
+// The round number itself only moves on once the store starts the round.
+func dealRound(l *model.Lobby) {
+	l.Dealer = l.NextDealer()
+
+	if len(l.Locations) > 0 {
+		l.CurrentLocation = l.Locations[rand.Intn(len(l.Locations))]
+	}
+
+	playersarray := append([]string(nil), l.AllPlayers...)
+	spyplayers := make([]string, 0, l.SpyCount())
+
+	for i := l.SpyCount(); i > 0 && len(playersarray) > 0; i-- {
+		a := rand.Intn(len(playersarray))
+		spyplayers = append(spyplayers, playersarray[a])
+		playersarray = append(playersarray[:a], playersarray[a+1:]...)
+	}
+
+	l.SpyPlayers = spyplayers
+
+	roles, ok := l.LocationRoles[l.CurrentLocation]
+	if !ok {
+		location, _ := model.FindLocation(model.DefaultLocations, l.CurrentLocation)
+		roles = location.Roles
 	}
+	l.AssignRoles(roles, rand.Perm)
 }
 
-// LocationsGenerator func
-func LocationsGenerator() ([]string, string) {
-	locations := []string{"Bank", "Hospital", "Military unit", "Casino",
-		"Hollywood", "Titanic", "The Death Star", "Hotel",
-		"Russian Railways", "Malibu Beach", "Police Station",
-		"Restaurant", "University", "Lyceum", "SPA", "Plane"}
+// LocationsGenerator draws a board of size locations from the catalogue
+// and picks the current location among them
+func LocationsGenerator(catalogue []model.Location, size int) ([]model.Location, model.Location) {
 	rand.Seed(time.Now().UnixNano())
-	locarray := locations
-	var finallocarray []string = make([]string, 0, 20)
-	for i := 12; i > 0; i-- {
-		a := rand.Intn(i)
+	locarray := append([]model.Location(nil), catalogue...)
+	var finallocarray []model.Location = make([]model.Location, 0, size)
+	for i := size; i > 0 && len(locarray) > 0; i-- {
+		a := rand.Intn(len(locarray))
 		finallocarray = append(finallocarray, locarray[a])
 		locarray = append(locarray[:a], locarray[a+1:]...)
 	}
-	b := rand.Intn(12)
+	b := rand.Intn(len(finallocarray))
 	return finallocarray, finallocarray[b]
 }
@@ -0,0 +1,534 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store/memstore"
+	"github.com/gorilla/websocket"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("token_password", "test")
+	os.Exit(m.Run())
+}
+
+func testServer(t *testing.T) (*server, *memstore.Store) {
+	t.Helper()
+
+	st := memstore.New()
+	t.Cleanup(func() { st.Listener().Close() })
+
+	return newServer(st, st.Listener(), NewConfig()), st
+}
+
+// testRequest builds a request sending body as JSON as the user holding token
+func testRequest(t *testing.T, method, path, token string, body interface{}) *http.Request {
+	t.Helper()
+
+	b := &bytes.Buffer{}
+	if body != nil {
+		if err := json.NewEncoder(b).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, b)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req
+}
+
+// do serves the request and decodes the JSON response
+func do(t *testing.T, s *server, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, testRequest(t, method, path, token, body))
+
+	response := map[string]interface{}{}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+
+	return rec.Code, response
+}
+
+// reply type
+type reply struct {
+	code     int
+	response map[string]interface{}
+}
+
+// connect joins the lobby in the background, as a successful join only
+// completes once the game starts. The request is cancelled when the test ends.
+func connect(t *testing.T, s *server, lobby, token string, body interface{}) <-chan reply {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req := testRequest(t, "POST", "/lobby/connect/"+lobby, token, body).WithContext(ctx)
+	replies := make(chan reply, 1)
+
+	go func() {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		response := map[string]interface{}{}
+		json.NewDecoder(rec.Body).Decode(&response)
+		replies <- reply{code: rec.Code, response: response}
+	}()
+
+	return replies
+}
+
+// waitPlayers waits until the lobby has n players
+func waitPlayers(t *testing.T, st *memstore.Store, lobby string, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		l, err := st.Lobby().FindByToken(context.Background(), lobby)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(l.AllPlayers) == n {
+			return
+		}
+	}
+
+	t.Fatalf("lobby %s never got %d players", lobby, n)
+}
+
+// signUp creates an account for login and returns its auth token
+func signUp(t *testing.T, s *server, login string) string {
+	t.Helper()
+
+	_, response := do(t, s, "POST", "/user/new", "", map[string]string{"login": login, "password": "password"})
+	account, ok := response["account"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("sign up %s: %v", login, response)
+	}
+
+	return account["token"].(string)
+}
+
+// createLobby creates a lobby hosted by the holder of token and returns its token
+func createLobby(t *testing.T, s *server, token string, body map[string]interface{}) string {
+	t.Helper()
+
+	_, response := do(t, s, "POST", "/lobby/create", token, body)
+	lobby, ok := response["lobby"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("create lobby: %v", response)
+	}
+
+	return lobby["token"].(string)
+}
+
+func TestServer_CreateUser(t *testing.T) {
+	s, _ := testServer(t)
+
+	signUp(t, s, "alice")
+
+	_, response := do(t, s, "POST", "/user/new", "", map[string]string{"login": "alice", "password": "password"})
+	if response["status"] != false {
+		t.Fatalf("duplicate login accepted: %v", response)
+	}
+
+	code, _ := do(t, s, "POST", "/lobby/create", "", map[string]interface{}{})
+	if code != http.StatusForbidden {
+		t.Fatalf("unauthenticated request: got %d", code)
+	}
+}
+
+func TestServer_PlayRound(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	lobby := createLobby(t, s, host, map[string]interface{}{})
+
+	players := []string{host}
+	for _, login := range []string{"bob", "carol"} {
+		players = append(players, signUp(t, s, login))
+	}
+
+	joins := []<-chan reply{}
+	for _, token := range players {
+		joins = append(joins, connect(t, s, lobby, token, nil))
+	}
+	waitPlayers(t, st, lobby, len(players))
+
+	if code, response := do(t, s, "POST", "/lobby/start/"+lobby, players[1], nil); code != http.StatusForbidden || response["code"] != codeNotHost {
+		t.Fatalf("player started the game: %d %v", code, response)
+	}
+
+	if code, response := do(t, s, "POST", "/lobby/start/"+lobby, host, nil); code != http.StatusConflict || response["code"] != codeNotReady {
+		t.Fatalf("game started before players were ready: %d %v", code, response)
+	}
+
+	if code, response := do(t, s, "POST", "/lobby/start/"+lobby, host, map[string]bool{"force": true}); code != http.StatusOK {
+		t.Fatalf("start: %d %v", code, response)
+	}
+
+	for _, join := range joins {
+		if r := <-join; r.code != http.StatusOK || r.response["lobby"] == nil {
+			t.Fatalf("connect: %d %v", r.code, r.response)
+		}
+	}
+
+	l, err := st.Lobby().FindByToken(context.Background(), lobby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Status != model.StatusInProgress || l.Round != 1 || len(l.SpyPlayers) != 1 || len(l.Roles) != 2 {
+		t.Fatalf("round was not dealt: %+v", l)
+	}
+
+	if _, response := do(t, s, "GET", "/lobby/scoreboard/"+lobby, host, nil); response["timeleft"] == nil || response["timeleft"].(float64) <= 0 {
+		t.Fatalf("scoreboard has no time left: %v", response)
+	}
+
+	tokens := map[string]string{"host": host, "bob": players[1], "carol": players[2]}
+	spy := l.SpyPlayers[0]
+	if code, response := do(t, s, "POST", "/lobby/checklocation/"+lobby, tokens[spy], map[string]string{"location": l.CurrentLocation}); code != http.StatusOK || response["timeleft"] == nil {
+		t.Fatalf("spy guess: %d %v", code, response)
+	}
+
+	_, response := do(t, s, "GET", "/lobby/history/"+lobby, host, nil)
+	rounds, ok := response["rounds"].([]interface{})
+	if !ok || len(rounds) != 1 {
+		t.Fatalf("history: %v", response)
+	}
+	round := rounds[0].(map[string]interface{})
+	if round["winner"] != model.WinnerSpy || round["location"] != l.CurrentLocation || round["points"].(map[string]interface{})[spy] != float64(4) {
+		t.Fatalf("round is not recorded: %v", round)
+	}
+
+	latecomer := signUp(t, s, "dave")
+	if code, response := do(t, s, "GET", "/lobby/history/"+lobby, latecomer, nil); code != http.StatusForbidden || response["code"] != codeNotMember {
+		t.Fatalf("history shown to a stranger: %d %v", code, response)
+	}
+	if code, response := do(t, s, "GET", "/lobby/scoreboard/"+lobby, latecomer, nil); code != http.StatusForbidden || response["code"] != codeNotMember {
+		t.Fatalf("scoreboard shown to a stranger: %d %v", code, response)
+	}
+
+	if r := <-connect(t, s, lobby, latecomer, nil); r.response["status"] != false {
+		t.Fatalf("joined a game in progress: %d %v", r.code, r.response)
+	}
+}
+
+func TestServer_KickPlayer(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	player := signUp(t, s, "bob")
+	lobby := createLobby(t, s, host, map[string]interface{}{})
+
+	hostJoin := connect(t, s, lobby, host, nil)
+	join := connect(t, s, lobby, player, nil)
+	waitPlayers(t, st, lobby, 2)
+
+	if code, response := do(t, s, "POST", "/lobby/kick/"+lobby, host, map[string]string{"login": "bob"}); code != http.StatusOK {
+		t.Fatalf("kick: %d %v", code, response)
+	}
+
+	if r := <-join; r.code != http.StatusForbidden || r.response["code"] != codeNotMember {
+		t.Fatalf("kicked player kept waiting: %d %v", r.code, r.response)
+	}
+
+	if r := <-connect(t, s, lobby, player, nil); r.code != http.StatusForbidden || r.response["code"] != codeBanned {
+		t.Fatalf("banned player rejoined: %d %v", r.code, r.response)
+	}
+
+	if code, response := do(t, s, "POST", "/lobby/unban/"+lobby, host, map[string]string{"login": "bob"}); code != http.StatusOK {
+		t.Fatalf("unban: %d %v", code, response)
+	}
+
+	connect(t, s, lobby, player, nil)
+	waitPlayers(t, st, lobby, 2)
+
+	if code, response := do(t, s, "POST", "/lobby/end/"+lobby, host, nil); code != http.StatusOK {
+		t.Fatalf("end: %d %v", code, response)
+	}
+
+	if r := <-hostJoin; r.code != http.StatusConflict || r.response["code"] != codeLobbyClosed {
+		t.Fatalf("host kept waiting on a closed lobby: %d %v", r.code, r.response)
+	}
+}
+
+func TestServer_RequestsKeepPresence(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	lobby := createLobby(t, s, host, map[string]interface{}{})
+	connect(t, s, lobby, host, nil)
+	waitPlayers(t, st, lobby, 1)
+
+	l, err := st.Lobby().FindByToken(context.Background(), lobby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Lobby().SetOnline(context.Background(), l, "host", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, response := do(t, s, "GET", "/lobby/scoreboard/"+lobby, host, nil); code != http.StatusOK {
+		t.Fatalf("scoreboard: %d %v", code, response)
+	}
+
+	l, err = st.Lobby().FindByToken(context.Background(), lobby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.Online["host"] {
+		t.Fatal("player making requests is shown as disconnected")
+	}
+}
+
+func TestServer_ProtectedLobby(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	player := signUp(t, s, "bob")
+	lobby := createLobby(t, s, host, map[string]interface{}{"password": "secret"})
+
+	if r := <-connect(t, s, lobby, player, map[string]string{"password": "wrong"}); r.code != http.StatusForbidden || r.response["code"] != codeWrongPassword {
+		t.Fatalf("wrong password accepted: %d %v", r.code, r.response)
+	}
+
+	connect(t, s, lobby, player, map[string]string{"password": "secret"})
+	waitPlayers(t, st, lobby, 1)
+
+	_, response := do(t, s, "GET", "/lobbies", player, nil)
+	if lobbies := response["lobbies"].([]interface{}); len(lobbies) != 0 {
+		t.Fatalf("protected lobby is listed: %v", lobbies)
+	}
+}
+
+func TestServer_WatchLobby(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	viewer := signUp(t, s, "carol")
+	lobby := createLobby(t, s, host, map[string]interface{}{"password": "secret"})
+
+	if code, response := do(t, s, "POST", "/lobby/watch/"+lobby, viewer, map[string]string{"password": "wrong"}); code != http.StatusForbidden || response["code"] != codeWrongPassword {
+		t.Fatalf("watched with a wrong password: %d %v", code, response)
+	}
+
+	if code, response := do(t, s, "POST", "/lobby/unwatch/"+lobby, viewer, nil); code != http.StatusForbidden || response["code"] != codeNotMember {
+		t.Fatalf("stopped watching without watching: %d %v", code, response)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code, response := do(t, s, "POST", "/lobby/watch/"+lobby, viewer, map[string]string{"password": "secret"}); code != http.StatusOK {
+			t.Fatalf("watch: %d %v", code, response)
+		}
+	}
+
+	l, err := st.Lobby().FindByToken(context.Background(), lobby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Spectators) != 1 || l.Spectators[0] != "carol" {
+		t.Fatalf("got spectators %v", l.Spectators)
+	}
+
+	if code, response := do(t, s, "POST", "/lobby/unwatch/"+lobby, viewer, nil); code != http.StatusOK {
+		t.Fatalf("unwatch: %d %v", code, response)
+	}
+
+	if l, _ := st.Lobby().FindByToken(context.Background(), lobby); len(l.Spectators) != 0 {
+		t.Fatalf("got spectators %v after unwatching", l.Spectators)
+	}
+}
+
+func TestServer_SocketQueryToken(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	lobby := createLobby(t, s, host, map[string]interface{}{})
+	connect(t, s, lobby, host, nil)
+	waitPlayers(t, st, lobby, 1)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/lobby/ws/" + lobby
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("socket opened without a token: %v", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?access_token="+host, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestServer_EventsQueryToken(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	lobby := createLobby(t, s, host, map[string]interface{}{})
+	connect(t, s, lobby, host, nil)
+	waitPlayers(t, st, lobby, 1)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/lobby/events/" + lobby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("stream opened without a token: %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/lobby/events/" + lobby + "?access_token=" + host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestServer_EventsResync(t *testing.T) {
+	s, st := testServer(t)
+
+	host := signUp(t, s, "host")
+	lobby := createLobby(t, s, host, map[string]interface{}{})
+	connect(t, s, lobby, host, nil)
+	waitPlayers(t, st, lobby, 1)
+
+	l, err := st.Lobby().FindByToken(context.Background(), lobby)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	testCases := []struct {
+		name   string
+		lastID int64
+		code   int
+	}{
+		{"up to date", l.EventSeq * eventsPerChange, http.StatusOK},
+		{"unknown to the server", 1, http.StatusGone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", ts.URL+"/lobby/events/"+lobby+"?access_token="+host, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Last-Event-ID", strconv.FormatInt(tc.lastID, 10))
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.code {
+				t.Fatalf("resume after %d: %d, want %d", tc.lastID, resp.StatusCode, tc.code)
+			}
+		})
+	}
+}
+
+func TestServer_ThrottleTokens(t *testing.T) {
+	st := memstore.New()
+	t.Cleanup(func() { st.Listener().Close() })
+
+	config := NewConfig()
+	config.JoinAttempts = 2
+	config.TrustedProxies = []string{"10.0.0.0/8"}
+	s := newServer(st, st.Listener(), config)
+
+	player := signUp(t, s, "bob")
+
+	guess := func(remoteAddr, forwardedFor string) int {
+		req := testRequest(t, "GET", "/lobby/scoreboard/unknown", player, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < config.JoinAttempts; i++ {
+		if code := guess("10.0.0.1:1234", "203.0.113.1"); code != http.StatusNotFound {
+			t.Fatalf("guess %d: got %d", i, code)
+		}
+	}
+
+	if code := guess("10.0.0.1:1234", "203.0.113.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("guessing went on: got %d", code)
+	}
+
+	if code := guess("10.0.0.1:1234", "203.0.113.2"); code != http.StatusNotFound {
+		t.Fatalf("other clients of the proxy are blocked: got %d", code)
+	}
+
+	if code := guess("198.51.100.1:1234", "203.0.113.1"); code != http.StatusNotFound {
+		t.Fatalf("untrusted X-Forwarded-For was used: got %d", code)
+	}
+
+	for i := 0; i < config.JoinAttempts; i++ {
+		if code, response := do(t, s, "POST", "/lobby/ready/unknown", player, map[string]bool{"ready": true}); code != http.StatusNotFound {
+			t.Fatalf("action on an unknown lobby: %d %v", code, response)
+		}
+	}
+	if code, _ := do(t, s, "POST", "/lobby/ready/unknown", player, map[string]bool{"ready": true}); code != http.StatusTooManyRequests {
+		t.Fatalf("guessing through actions went on: got %d", code)
+	}
+}
+
+func TestServer_SweepRounds(t *testing.T) {
+	ctx := context.Background()
+	st := memstore.New()
+	t.Cleanup(func() { st.Listener().Close() })
+
+	// A round whose clock ran out while no server was running to end it
+	l := &model.Lobby{Token: "abcdef", Host: "host", MinPl: 3, AmountPl: 8, AmountSpy: 1, Status: model.StatusCreated}
+	if err := st.Lobby().Create(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+	for _, login := range []string{"host", "bob", "carol"} {
+		if err := st.Lobby().ConnectUserToLobby(ctx, l, login); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.Lobby().StartGame(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+
+	newServer(st, st.Listener(), NewConfig())
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if status, _ := st.Lobby().CheckStatus(ctx, l.Token); status == model.StatusVoting {
+			return
+		}
+	}
+
+	t.Fatal("the expired round was never ended")
+}
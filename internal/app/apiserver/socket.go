@@ -0,0 +1,111 @@
+package apiserver
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/jwt"
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// lobbySocket func
+func (s *server) lobbySocket() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Browsers can't set headers on WebSocket requests, so the auth token
+		// may come in the query string
+		r, err := jwt.Authenticate(r)
+		if err != nil {
+			u.Error(w, http.StatusForbidden, err)
+			return
+		}
+
+		vars := mux.Vars(r)
+		token := vars["token"]
+
+		currentlobby, err := s.store.Lobby().FindByToken(r.Context(), token)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		user, err := s.lobbyMember(r, currentlobby)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		sub, missed, err := s.resume(r.Context(), token, user.Login, lastEventID(r))
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		defer s.unsubscribe(token, sub)
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			s.logger.Warnf("websocket upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for _, e := range missed {
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn.SetReadLimit(512)
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(pongWait))
+			})
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case e := <-sub.events:
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := conn.WriteJSON(e); err != nil {
+					return
+				}
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package apiserver
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// throttle counts failed attempts per client address and blocks an address
+// once it has failed too often within the window. A limit of 0 disables it.
+type throttle struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	attempts map[string]*attempts
+	// proxies are the networks of the reverse proxies trusted to report the
+	// client address in the X-Forwarded-For and X-Real-IP headers
+	proxies []*net.IPNet
+}
+
+// attempts struct
+type attempts struct {
+	failed int
+	reset  time.Time
+}
+
+func newThrottle(limit int, window time.Duration, proxies []*net.IPNet) *throttle {
+	return &throttle{
+		limit:    limit,
+		window:   window,
+		attempts: make(map[string]*attempts),
+		proxies:  proxies,
+	}
+}
+
+// allow reports whether the address may make another attempt
+func (t *throttle) allow(addr string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a, ok := t.attempts[addr]
+	if !ok {
+		return true
+	}
+
+	if time.Now().After(a.reset) {
+		delete(t.attempts, addr)
+		return true
+	}
+
+	return t.limit <= 0 || a.failed < t.limit
+}
+
+// fail records a failed attempt of the address
+func (t *throttle) fail(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for k, a := range t.attempts {
+		if now.After(a.reset) {
+			delete(t.attempts, k)
+		}
+	}
+
+	a, ok := t.attempts[addr]
+	if !ok {
+		a = &attempts{reset: now.Add(t.window)}
+		t.attempts[addr] = a
+	}
+	a.failed++
+}
+
+// clientAddr returns the IP address of the client making the request. When
+// the request comes through trusted proxies, that is the last address in
+// X-Forwarded-For that isn't one of theirs, or else X-Real-IP.
+func (t *throttle) clientAddr(r *http.Request) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+
+	if !t.trusted(addr) {
+		return addr
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		if !t.trusted(hop) {
+			return hop
+		}
+		addr = hop
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+
+	return addr
+}
+
+// trusted reports whether addr belongs to a trusted proxy
+func (t *throttle) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range t.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseProxies parses the addresses and CIDR networks of trusted proxies
+func parseProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
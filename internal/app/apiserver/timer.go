@@ -0,0 +1,117 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+)
+
+// sweepPeriod is how often the server looks for rounds whose clock ran out
+// without a timer ending them
+const sweepPeriod = 5 * time.Second
+
+// clock struct
+type clock struct {
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newClock() *clock {
+	return &clock{
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// schedule runs f at the given time, replacing the pending timer of the lobby
+func (c *clock) schedule(token string, at time.Time, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t, ok := c.timers[token]; ok {
+		t.Stop()
+	}
+
+	c.timers[token] = time.AfterFunc(time.Until(at), func() {
+		c.mu.Lock()
+		delete(c.timers, token)
+		c.mu.Unlock()
+
+		f()
+	})
+}
+
+// cancel func
+func (c *clock) cancel(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t, ok := c.timers[token]; ok {
+		t.Stop()
+		delete(c.timers, token)
+	}
+}
+
+// scheduleRound arms the round timer of a lobby whose clock is running and
+// disarms it otherwise. Every replica that sees a change arms its own timer,
+// the conditional TimeUp update lets only one of them end the round.
+func (s *server) scheduleRound(l *model.Lobby) {
+	if l.Status != model.StatusInProgress || l.Paused || l.Deadline == nil {
+		s.clock.cancel(l.Token)
+		return
+	}
+
+	token := l.Token
+	s.clock.schedule(token, *l.Deadline, func() {
+		s.expireRound(token)
+	})
+}
+
+// sweepRounds ends the rounds whose clock ran out until done is closed. The
+// timers armed by scheduleRound live in a single process, so a round whose
+// timer was lost to a restart or to a replica going down is ended here.
+func (s *server) sweepRounds(done <-chan struct{}) {
+	ticker := time.NewTicker(sweepPeriod)
+	defer ticker.Stop()
+
+	for {
+		tokens, err := s.store.Lobby().FindExpired(context.Background(), time.Now())
+		if err != nil {
+			s.logger.Errorf("unable to find expired rounds: %v", err)
+		}
+
+		for _, token := range tokens {
+			s.expireRound(token)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// expireRound moves the lobby to the voting phase once its round clock ran out
+func (s *server) expireRound(token string) {
+	l, err := s.store.Lobby().FindByToken(context.Background(), token)
+	if err != nil {
+		s.logger.Errorf("unable to load lobby %s: %v", token, err)
+		return
+	}
+
+	if l.Status != model.StatusInProgress || l.Paused || l.Deadline == nil {
+		return
+	}
+
+	if !l.TimeUp() {
+		s.scheduleRound(l)
+		return
+	}
+
+	if err := s.store.Lobby().TimeUp(context.Background(), l); err != nil && !errors.Is(err, model.ErrIllegalTransition) {
+		s.logger.Errorf("unable to end round of lobby %s: %v", token, err)
+	}
+}
@@ -2,7 +2,7 @@ package jwt
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -13,6 +13,21 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
+// QueryParam is the query parameter carrying the auth token of the clients
+// that can't set the Authorization header, such as browser WebSocket and
+// EventSource clients
+const QueryParam = "access_token"
+
+// streamPaths are the endpoints accepting the auth token in QueryParam. They
+// authenticate requests themselves with Authenticate.
+var streamPaths = []string{"/lobby/ws/", "/lobby/events/"}
+
+var (
+	errMissingToken   = errors.New("Missing auth token")
+	errMalformedToken = errors.New("Malformed authentication token")
+	errInvalidToken   = errors.New("Token is not valid.")
+)
+
 // JwtAuthentication var
 var JwtAuthentication = func(next http.Handler) http.Handler {
 
@@ -29,6 +44,14 @@ var JwtAuthentication = func(next http.Handler) http.Handler {
 			}
 		}
 
+		for _, prefix := range streamPaths {
+
+			if strings.HasPrefix(requestPath, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
 		response := make(map[string]interface{})
 		tokenHeader := r.Header.Get("Authorization")
 
@@ -72,9 +95,44 @@ var JwtAuthentication = func(next http.Handler) http.Handler {
 			return
 		}
 
-		fmt.Sprintf("User %d", tk.UserID)
 		ctx := context.WithValue(r.Context(), "user", tk.UserID)
 		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Authenticate authenticates the request by the token in its Authorization
+// header or, failing that, in the QueryParam query parameter, and returns it
+// with the id of the user in its context
+func Authenticate(r *http.Request) (*http.Request, error) {
+	tokenPart := r.URL.Query().Get(QueryParam)
+
+	if tokenHeader := r.Header.Get("Authorization"); tokenHeader != "" {
+		splitted := strings.Split(tokenHeader, " ")
+		if len(splitted) != 2 {
+			return nil, errMalformedToken
+		}
+		tokenPart = splitted[1]
+	}
+
+	if tokenPart == "" {
+		return nil, errMissingToken
+	}
+
+	tk := &model.Token{}
+
+	token, err := jwt.ParseWithClaims(tokenPart, tk, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("token_password")), nil
+	})
+
+	if err != nil {
+		return nil, errMalformedToken
+	}
+
+	if !token.Valid {
+		return nil, errInvalidToken
+	}
+
+	ctx := context.WithValue(r.Context(), "user", tk.UserID)
+	return r.WithContext(ctx), nil
+}
@@ -1,13 +1,213 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Player counts every lobby must respect
+const (
+	MinPlayers = 3
+	MaxPlayers = 20
+)
+
+var (
+	// ErrNotEnoughPlayers error
+	ErrNotEnoughPlayers = errors.New("Not enough players to start")
+	// ErrTooManySpies error
+	ErrTooManySpies = errors.New("There must be fewer spies than players")
+	// ErrNotReady error
+	ErrNotReady = errors.New("Not every player is ready")
+)
+
 // Lobby type
 type Lobby struct {
-	Token           string   `json:"token"`
-	Locations       []string `json:"locations"`
-	CurrentLocation string   `json:"currentloc"`
-	AmountPl        int      `json:"amountpl"`
-	AmountSpy       int      `json:"amountspy"`
-	SpyPlayers      []string `json:"spyplayers"`
-	AllPlayers      []string `json:"allplayers"`
-	Status          string   `json:"status"`
+	Token           string              `json:"token"`
+	Host            string              `json:"host"`
+	Public          bool                `json:"public"`
+	Password        string              `json:"-"`
+	Locations       []string            `json:"locations"`
+	CurrentLocation string              `json:"currentloc"`
+	MinPl           int                 `json:"minpl"`
+	AmountPl        int                 `json:"amountpl"`
+	AmountSpy       int                 `json:"amountspy"`
+	SpyPlayers      []string            `json:"spyplayers"`
+	AllPlayers      []string            `json:"allplayers"`
+	Spectators      []string            `json:"spectators"`
+	Banned          []string            `json:"banned"`
+	Ready           map[string]bool     `json:"ready"`
+	Online          map[string]bool     `json:"online"`
+	Status          LobbyStatus         `json:"status"`
+	Winner          string              `json:"winner"`
+	Accuser         string              `json:"accuser"`
+	Suspect         string              `json:"suspect"`
+	Votes           map[string]bool     `json:"votes"`
+	RoundDuration   int                 `json:"roundduration"`
+	Deadline        *time.Time          `json:"deadline,omitempty"`
+	Paused          bool                `json:"paused"`
+	TimeLeft        int                 `json:"timeleft"`
+	Rounds          int                 `json:"rounds"`
+	Round           int                 `json:"round"`
+	Dealer          string              `json:"dealer"`
+	Outcome         string              `json:"outcome"`
+	Scores          map[string]int      `json:"scores"`
+	Roles           map[string]string   `json:"roles"`
+	LocationRoles   map[string][]string `json:"-"`
+	// EventSeq is the Seq of the last change notified for the lobby
+	EventSeq int64 `json:"-"`
+}
+
+// DefaultSpies returns the number of spies for a round with the given number
+// of players, one for every six players but at least one
+func DefaultSpies(players int) int {
+	if spies := players / 6; spies > 1 {
+		return spies
+	}
+	return 1
+}
+
+// SpyCount returns the number of spies dealt in the next round. AmountSpy
+// is used when the host chose it, otherwise it follows the joined players.
+func (l *Lobby) SpyCount() int {
+	if l.AmountSpy > 0 {
+		return l.AmountSpy
+	}
+	return DefaultSpies(len(l.AllPlayers))
+}
+
+// CheckStart returns an error if a round can't be dealt to the joined players.
+// The host may start anywhere between MinPl and AmountPl players.
+func (l *Lobby) CheckStart() error {
+	players := len(l.AllPlayers)
+
+	least := l.MinPl
+	if least < MinPlayers {
+		least = MinPlayers
+	}
+
+	if players < least {
+		return fmt.Errorf("%w: %d of at least %d have joined", ErrNotEnoughPlayers, players, least)
+	}
+
+	if l.SpyCount() >= players {
+		return fmt.Errorf("%w: %d spies for %d players", ErrTooManySpies, l.SpyCount(), players)
+	}
+
+	return nil
+}
+
+// Unready returns the players present in the lobby who have not declared
+// themselves ready. Disconnected players don't hold up the start.
+func (l *Lobby) Unready() []string {
+	unready := []string{}
+	for _, player := range l.AllPlayers {
+		if l.Online[player] && !l.Ready[player] {
+			unready = append(unready, player)
+		}
+	}
+	return unready
+}
+
+// Protected reports whether joining the lobby needs a password
+func (l *Lobby) Protected() bool {
+	return l.Password != ""
+}
+
+// EncryptPassword func
+func (l *Lobby) EncryptPassword() (map[string]interface{}, bool) {
+
+	if len(l.Password) < 4 {
+		return u.Message(false, "Lobby password must be at least 4 characters long"), false
+	}
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(l.Password), bcrypt.DefaultCost)
+	l.Password = string(hashedPassword)
+	return u.Message(false, "Encrypted passed"), true
+}
+
+// ComparePassword reports whether password opens the lobby. A lobby without
+// a password is open to everyone.
+func (l *Lobby) ComparePassword(password string) bool {
+	if !l.Protected() {
+		return true
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(l.Password), []byte(password)) == nil
+}
+
+// IsHost func
+func (l *Lobby) IsHost(login string) bool {
+	return login != "" && l.Host == login
+}
+
+// Successor returns the player who becomes host when login leaves, which is
+// the earliest joined of the remaining players, or "" if nobody remains
+func (l *Lobby) Successor(login string) string {
+	for _, player := range l.AllPlayers {
+		if player != login {
+			return player
+		}
+	}
+	return ""
+}
+
+// CountDown sets TimeLeft to the seconds remaining in the round at now.
+// A stopped clock keeps the TimeLeft it was stopped with.
+func (l *Lobby) CountDown(now time.Time) {
+	if l.Paused || l.Deadline == nil {
+		return
+	}
+
+	left := l.Deadline.Sub(now)
+	if left < 0 {
+		left = 0
+	}
+
+	l.TimeLeft = int((left + time.Second - 1) / time.Second)
+}
+
+// TimeUp reports whether the round clock has run out
+func (l *Lobby) TimeUp() bool {
+	return (l.Deadline != nil || l.Paused) && l.TimeLeft <= 0
+}
+
+// Voters returns the players voting on the current accusation, which is
+// everyone except the suspect
+func (l *Lobby) Voters() []string {
+	voters := make([]string, 0, len(l.AllPlayers))
+	for _, player := range l.AllPlayers {
+		if player != l.Suspect {
+			voters = append(voters, player)
+		}
+	}
+	return voters
+}
+
+// Verdict reports whether the vote on the current accusation is decided and
+// whether it convicted the suspect. A single vote against acquits, while
+// a conviction needs every voter to agree.
+func (l *Lobby) Verdict() (decided bool, convicted bool) {
+	voters := l.Voters()
+
+	for _, voter := range voters {
+		vote, ok := l.Votes[voter]
+		if !ok {
+			continue
+		}
+		if !vote {
+			return true, false
+		}
+	}
+
+	for _, voter := range voters {
+		if _, ok := l.Votes[voter]; !ok {
+			return false, false
+		}
+	}
+
+	return true, true
 }
@@ -0,0 +1,195 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+)
+
+func TestLobby_Verdict(t *testing.T) {
+	testCases := []struct {
+		name      string
+		votes     map[string]bool
+		decided   bool
+		convicted bool
+	}{
+		{
+			name:  "only accuser voted",
+			votes: map[string]bool{"TestPlayer2": true},
+		},
+		{
+			name:    "one voter disagrees",
+			votes:   map[string]bool{"TestPlayer2": true, "TestPlayer3": false},
+			decided: true,
+		},
+		{
+			name:      "unanimous without suspect",
+			votes:     map[string]bool{"TestPlayer2": true, "TestPlayer3": true, "TestPlayer4": true, "TestPlayer5": true},
+			decided:   true,
+			convicted: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := model.TestLobby(t)
+			l.Accuser = "TestPlayer2"
+			l.Suspect = "TestPlayer1"
+			l.Votes = tc.votes
+
+			decided, convicted := l.Verdict()
+			if decided != tc.decided || convicted != tc.convicted {
+				t.Fatalf("Verdict() = (%v, %v), want (%v, %v)", decided, convicted, tc.decided, tc.convicted)
+			}
+		})
+	}
+}
+
+func TestLobby_CountDown(t *testing.T) {
+	now := time.Now()
+
+	l := model.TestLobby(t)
+	deadline := now.Add(90*time.Second + 500*time.Millisecond)
+	l.Deadline = &deadline
+
+	l.CountDown(now)
+	if l.TimeLeft != 91 {
+		t.Fatalf("TimeLeft = %d, want 91", l.TimeLeft)
+	}
+	if l.TimeUp() {
+		t.Fatal("TimeUp() = true before the deadline")
+	}
+
+	l.CountDown(now.Add(2 * time.Minute))
+	if l.TimeLeft != 0 || !l.TimeUp() {
+		t.Fatalf("TimeLeft = %d, TimeUp() = %v after the deadline", l.TimeLeft, l.TimeUp())
+	}
+
+	l.Deadline = nil
+	l.Paused = true
+	l.TimeLeft = 30
+	l.CountDown(now.Add(time.Hour))
+	if l.TimeLeft != 30 {
+		t.Fatalf("paused TimeLeft = %d, want 30", l.TimeLeft)
+	}
+}
+
+func TestLobby_AssignRoles(t *testing.T) {
+	l := model.TestLobby(t)
+	identity := func(n int) []int {
+		order := make([]int, n)
+		for i := range order {
+			order[i] = i
+		}
+		return order
+	}
+
+	l.AssignRoles([]string{"Teller", "Robber"}, identity)
+
+	if _, ok := l.Roles["TestPlayer1"]; ok {
+		t.Fatal("spy has been given a role")
+	}
+
+	want := map[string]string{"TestPlayer2": "Teller", "TestPlayer3": "Robber", "TestPlayer4": "Teller", "TestPlayer5": "Robber"}
+	for player, role := range want {
+		if l.Roles[player] != role {
+			t.Fatalf("role of %s = %q, want %q", player, l.Roles[player], role)
+		}
+	}
+}
+
+func TestLobby_Successor(t *testing.T) {
+	l := model.TestLobby(t)
+	l.Host = "TestPlayer1"
+
+	if got := l.Successor("TestPlayer1"); got != "TestPlayer2" {
+		t.Fatalf("successor of the host = %q, want TestPlayer2", got)
+	}
+
+	if got := l.Successor("TestPlayer3"); got != "TestPlayer1" {
+		t.Fatalf("successor of a player = %q, want TestPlayer1", got)
+	}
+
+	l.AllPlayers = []string{"TestPlayer1"}
+	if got := l.Successor("TestPlayer1"); got != "" {
+		t.Fatalf("successor of the last player = %q, want none", got)
+	}
+}
+
+func TestLobby_CheckStart(t *testing.T) {
+	testCases := []struct {
+		name      string
+		players   []string
+		amountSpy int
+		spies     int
+		err       error
+	}{
+		{"full lobby", []string{"a", "b", "c", "d", "e"}, 0, 1, nil},
+		{"minimum reached", []string{"a", "b", "c"}, 0, 1, nil},
+		{"below minimum", []string{"a", "b"}, 0, 1, model.ErrNotEnoughPlayers},
+		{"chosen spies", []string{"a", "b", "c", "d"}, 2, 2, nil},
+		{"a spy for everyone", []string{"a", "b", "c"}, 3, 3, model.ErrTooManySpies},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := model.TestLobby(t)
+			l.AllPlayers = tc.players
+			l.AmountSpy = tc.amountSpy
+
+			if got := l.SpyCount(); got != tc.spies {
+				t.Fatalf("spies = %d, want %d", got, tc.spies)
+			}
+
+			if err := l.CheckStart(); !errors.Is(err, tc.err) {
+				t.Fatalf("error = %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestDefaultSpies(t *testing.T) {
+	for players, spies := range map[int]int{3: 1, 11: 1, 12: 2, 20: 3} {
+		if got := model.DefaultSpies(players); got != spies {
+			t.Errorf("DefaultSpies(%d) = %d, want %d", players, got, spies)
+		}
+	}
+}
+
+func TestLobby_Unready(t *testing.T) {
+	l := model.TestLobby(t)
+	l.AllPlayers = []string{"TestPlayer1", "TestPlayer2", "TestPlayer3", "TestPlayer4"}
+	l.Ready = map[string]bool{"TestPlayer1": true, "TestPlayer2": false}
+	l.Online = map[string]bool{"TestPlayer1": true, "TestPlayer2": true, "TestPlayer3": true}
+
+	unready := l.Unready()
+	if len(unready) != 2 || unready[0] != "TestPlayer2" || unready[1] != "TestPlayer3" {
+		t.Fatalf("unready = %v, want [TestPlayer2 TestPlayer3]", unready)
+	}
+}
+
+func TestLobby_ComparePassword(t *testing.T) {
+	l := model.TestLobby(t)
+	if !l.ComparePassword("") {
+		t.Fatal("a lobby without a password must be open")
+	}
+
+	l.Password = "secret"
+	if _, ok := l.EncryptPassword(); !ok {
+		t.Fatal("unable to encrypt the lobby password")
+	}
+
+	if l.Password == "secret" {
+		t.Fatal("the lobby password is stored in plain text")
+	}
+
+	if !l.ComparePassword("secret") {
+		t.Fatal("the right password is rejected")
+	}
+
+	if l.ComparePassword("guess") {
+		t.Fatal("a wrong password is accepted")
+	}
+}
@@ -0,0 +1,65 @@
+package model
+
+// Location type
+type Location struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+// DefaultLocations is the built-in location catalogue
+var DefaultLocations = []Location{
+	{Name: "Bank", Roles: []string{"Teller", "Security Guard", "Robber", "Manager", "Consultant", "Armored Car Driver", "Customer"}},
+	{Name: "Hospital", Roles: []string{"Surgeon", "Nurse", "Patient", "Therapist", "Intern", "Pathologist", "Chief Physician"}},
+	{Name: "Military unit", Roles: []string{"Colonel", "Sergeant", "Private", "Medic", "Tank Driver", "Sniper", "Cook"}},
+	{Name: "Casino", Roles: []string{"Croupier", "Gambler", "Bartender", "Security Guard", "Manager", "Cheater", "Bouncer"}},
+	{Name: "Hollywood", Roles: []string{"Director", "Actor", "Stuntman", "Cameraman", "Producer", "Makeup Artist", "Screenwriter"}},
+	{Name: "Titanic", Roles: []string{"Captain", "Stoker", "Musician", "Cabin Boy", "Rich Passenger", "Stowaway", "Lookout"}},
+	{Name: "The Death Star", Roles: []string{"Sith Lord", "Stormtrooper", "Officer", "Engineer", "Rebel Spy", "Gunner", "Droid"}},
+	{Name: "Hotel", Roles: []string{"Doorman", "Receptionist", "Maid", "Guest", "Bellboy", "Manager", "Bartender"}},
+	{Name: "Russian Railways", Roles: []string{"Conductor", "Train Driver", "Passenger", "Ticket Inspector", "Dining Car Cook", "Stationmaster", "Border Guard"}},
+	{Name: "Malibu Beach", Roles: []string{"Lifeguard", "Surfer", "Tourist", "Ice Cream Seller", "Photographer", "Volleyball Player", "Thief"}},
+	{Name: "Police Station", Roles: []string{"Detective", "Lawyer", "Journalist", "Criminal", "Archivist", "Patrol Officer", "Chief"}},
+	{Name: "Restaurant", Roles: []string{"Chef", "Waiter", "Sommelier", "Musician", "Critic", "Dishwasher", "Customer"}},
+	{Name: "University", Roles: []string{"Professor", "Student", "Dean", "Graduate", "Janitor", "Librarian", "Lab Assistant"}},
+	{Name: "Lyceum", Roles: []string{"Teacher", "Pupil", "Headmaster", "Security Guard", "Janitor", "Parent", "School Nurse"}},
+	{Name: "SPA", Roles: []string{"Masseur", "Customer", "Manicurist", "Stylist", "Receptionist", "Beautician", "Sauna Attendant"}},
+	{Name: "Plane", Roles: []string{"Pilot", "Co-Pilot", "Flight Attendant", "First Class Passenger", "Economy Class Passenger", "Air Marshal", "Mechanic"}},
+}
+
+// FindLocation returns the location named name from the catalogue
+func FindLocation(catalogue []Location, name string) (Location, bool) {
+	for _, location := range catalogue {
+		if location.Name == name {
+			return location, true
+		}
+	}
+	return Location{}, false
+}
+
+// AssignRoles gives every peaceful player a role at the lobby's current
+// location from roles, shuffled by perm. Roles are reused when there are
+// more peaceful players than roles. Spies get no role.
+func (l *Lobby) AssignRoles(roles []string, perm func(int) []int) {
+	l.Roles = make(map[string]string)
+	if len(roles) == 0 {
+		return
+	}
+
+	order := perm(len(roles))
+	i := 0
+	for _, player := range l.AllPlayers {
+		spy := false
+		for _, s := range l.SpyPlayers {
+			if s == player {
+				spy = true
+				break
+			}
+		}
+		if spy {
+			continue
+		}
+
+		l.Roles[player] = roles[order[i%len(order)]]
+		i++
+	}
+}
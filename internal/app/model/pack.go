@@ -0,0 +1,70 @@
+package model
+
+import (
+	"fmt"
+
+	u "github.com/TOIFLMSC/spyfall-web-backend/internal/app/utils"
+)
+
+// Limits of a location pack
+const (
+	MaxPackLocations = 100
+	MaxPackRoles     = 20
+)
+
+// Pack type
+type Pack struct {
+	ID        int        `json:"id"`
+	OwnerID   int        `json:"ownerid"`
+	Name      string     `json:"name"`
+	Locations []Location `json:"locations"`
+}
+
+// Validate func
+func (pack *Pack) Validate() (map[string]interface{}, bool) {
+
+	if pack.Name == "" {
+		return u.Message(false, "Pack name is required"), false
+	}
+
+	if len(pack.Locations) == 0 || len(pack.Locations) > MaxPackLocations {
+		return u.Message(false, fmt.Sprintf("Pack must have from 1 to %d locations", MaxPackLocations)), false
+	}
+
+	names := make(map[string]bool)
+	for _, location := range pack.Locations {
+		if location.Name == "" {
+			return u.Message(false, "Location name is required"), false
+		}
+
+		if names[location.Name] {
+			return u.Message(false, fmt.Sprintf("Location %s is listed twice", location.Name)), false
+		}
+		names[location.Name] = true
+
+		if len(location.Roles) > MaxPackRoles {
+			return u.Message(false, fmt.Sprintf("Location %s has more than %d roles", location.Name, MaxPackRoles)), false
+		}
+	}
+
+	return u.Message(false, "Requirement passed"), true
+}
+
+// MergeLocations joins the locations of packs, keeping the first location
+// of each name
+func MergeLocations(packs ...[]Location) []Location {
+	var merged []Location
+	names := make(map[string]bool)
+
+	for _, pack := range packs {
+		for _, location := range pack {
+			if names[location.Name] {
+				continue
+			}
+			names[location.Name] = true
+			merged = append(merged, location)
+		}
+	}
+
+	return merged
+}
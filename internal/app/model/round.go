@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Round type is a finished round of a match as kept in the lobby history
+type Round struct {
+	Number    int               `json:"number"`
+	Dealer    string            `json:"dealer"`
+	Location  string            `json:"location"`
+	Spies     []string          `json:"spies"`
+	Roles     map[string]string `json:"roles"`
+	Winner    string            `json:"winner"`
+	Outcome   string            `json:"outcome"`
+	Points    map[string]int    `json:"points"`
+	StartedAt time.Time         `json:"startedat"`
+	EndedAt   *time.Time        `json:"endedat,omitempty"`
+}
@@ -0,0 +1,88 @@
+package model
+
+import "sort"
+
+// Round outcomes
+const (
+	OutcomeSpyGuessed      = "spy_guessed"
+	OutcomeSpyMissed       = "spy_missed"
+	OutcomeSpyCaught       = "spy_caught"
+	OutcomeWrongConviction = "wrong_conviction"
+	OutcomeSpyNotIndicted  = "spy_not_indicted"
+)
+
+// Score type
+type Score struct {
+	Login  string `json:"login"`
+	Points int    `json:"points"`
+}
+
+// Points returns the points each player earns for the finished round
+// following the official Spyfall rules
+func (l *Lobby) Points() map[string]int {
+	points := make(map[string]int)
+
+	spyPoints := 0
+	switch l.Outcome {
+	case OutcomeSpyGuessed, OutcomeWrongConviction:
+		spyPoints = 4
+	case OutcomeSpyNotIndicted:
+		spyPoints = 2
+	}
+
+	for _, player := range l.AllPlayers {
+		spy := false
+		for _, s := range l.SpyPlayers {
+			if s == player {
+				spy = true
+				break
+			}
+		}
+
+		switch {
+		case spy:
+			points[player] = spyPoints
+		case l.Outcome == OutcomeSpyCaught && player == l.Accuser:
+			points[player] = 2
+		case l.Outcome == OutcomeSpyCaught || l.Outcome == OutcomeSpyMissed:
+			points[player] = 1
+		}
+	}
+
+	return points
+}
+
+// Scoreboard returns the match scores, best first
+func (l *Lobby) Scoreboard() []Score {
+	scoreboard := make([]Score, 0, len(l.AllPlayers))
+	for _, player := range l.AllPlayers {
+		scoreboard = append(scoreboard, Score{Login: player, Points: l.Scores[player]})
+	}
+
+	sort.SliceStable(scoreboard, func(i, j int) bool {
+		return scoreboard[i].Points > scoreboard[j].Points
+	})
+
+	return scoreboard
+}
+
+// MatchOver reports whether the last round of the match has been played
+func (l *Lobby) MatchOver() bool {
+	return l.Status == StatusFinished && l.Round >= l.Rounds
+}
+
+// NextDealer returns the player who deals the next round, the one after
+// the current dealer in joining order
+func (l *Lobby) NextDealer() string {
+	if len(l.AllPlayers) == 0 {
+		return ""
+	}
+
+	for i, player := range l.AllPlayers {
+		if player == l.Dealer {
+			return l.AllPlayers[(i+1)%len(l.AllPlayers)]
+		}
+	}
+
+	return l.AllPlayers[0]
+}
@@ -0,0 +1,74 @@
+package model_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+)
+
+func TestLobby_Points(t *testing.T) {
+	testCases := []struct {
+		outcome string
+		want    map[string]int
+	}{
+		{
+			outcome: model.OutcomeSpyGuessed,
+			want:    map[string]int{"TestPlayer1": 4},
+		},
+		{
+			outcome: model.OutcomeWrongConviction,
+			want:    map[string]int{"TestPlayer1": 4},
+		},
+		{
+			outcome: model.OutcomeSpyNotIndicted,
+			want:    map[string]int{"TestPlayer1": 2},
+		},
+		{
+			outcome: model.OutcomeSpyCaught,
+			want:    map[string]int{"TestPlayer1": 0, "TestPlayer2": 2, "TestPlayer3": 1, "TestPlayer4": 1, "TestPlayer5": 1},
+		},
+		{
+			outcome: model.OutcomeSpyMissed,
+			want:    map[string]int{"TestPlayer1": 0, "TestPlayer2": 1, "TestPlayer3": 1, "TestPlayer4": 1, "TestPlayer5": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.outcome, func(t *testing.T) {
+			l := model.TestLobby(t)
+			l.Accuser = "TestPlayer2"
+			l.Outcome = tc.outcome
+
+			got := l.Points()
+			for player, points := range got {
+				if points == 0 {
+					delete(got, player)
+				}
+			}
+			for player, points := range tc.want {
+				if points == 0 {
+					delete(tc.want, player)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Points() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLobby_NextDealer(t *testing.T) {
+	l := model.TestLobby(t)
+
+	l.Dealer = ""
+	if got := l.NextDealer(); got != "TestPlayer1" {
+		t.Fatalf("first dealer = %s, want TestPlayer1", got)
+	}
+
+	l.Dealer = "TestPlayer5"
+	if got := l.NextDealer(); got != "TestPlayer1" {
+		t.Fatalf("dealer after TestPlayer5 = %s, want TestPlayer1", got)
+	}
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LobbyStatus type
+type LobbyStatus string
+
+// Lobby statuses
+const (
+	StatusCreated    LobbyStatus = "Created"
+	StatusWaiting    LobbyStatus = "Waiting"
+	StatusInProgress LobbyStatus = "InProgress"
+	StatusVoting     LobbyStatus = "Voting"
+	StatusFinished   LobbyStatus = "Finished"
+	StatusAbandoned  LobbyStatus = "Abandoned"
+)
+
+// Round winners
+const (
+	WinnerSpy      = "spy"
+	WinnerPeaceful = "peaceful"
+)
+
+// ErrIllegalTransition error
+var ErrIllegalTransition = errors.New("Illegal lobby status transition")
+
+// transitions lists the statuses each status may move to
+var transitions = map[LobbyStatus][]LobbyStatus{
+	StatusCreated:    {StatusWaiting, StatusAbandoned},
+	StatusWaiting:    {StatusInProgress, StatusAbandoned},
+	StatusInProgress: {StatusVoting, StatusFinished, StatusAbandoned},
+	StatusVoting:     {StatusInProgress, StatusFinished, StatusAbandoned},
+	StatusFinished:   {StatusInProgress, StatusAbandoned},
+	StatusAbandoned:  {},
+}
+
+// CanTransition func
+func (s LobbyStatus) CanTransition(to LobbyStatus) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Joinable reports whether players may still join a lobby in this status
+func (s LobbyStatus) Joinable() bool {
+	return s == StatusCreated || s == StatusWaiting
+}
+
+// CheckTransition returns an error if the lobby can't move to status to.
+// A finished lobby only starts again while its match has rounds left.
+func (l *Lobby) CheckTransition(to LobbyStatus) error {
+	if !l.Status.CanTransition(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, l.Status, to)
+	}
+
+	if l.Status == StatusFinished && to == StatusInProgress && l.Round >= l.Rounds {
+		return fmt.Errorf("%w: the match is over", ErrIllegalTransition)
+	}
+
+	return nil
+}
+
+// Transition moves the lobby to status to, rejecting illegal transitions
+func (l *Lobby) Transition(to LobbyStatus) error {
+	if err := l.CheckTransition(to); err != nil {
+		return err
+	}
+
+	l.Status = to
+	return nil
+}
+
+// Result func
+func (l *Lobby) Result() string {
+	switch l.Winner {
+	case WinnerSpy:
+		return "Spy won"
+	case WinnerPeaceful:
+		return "Peaceful won"
+	}
+	return string(l.Status)
+}
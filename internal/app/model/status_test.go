@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+)
+
+func TestLobby_Transition(t *testing.T) {
+	testCases := []struct {
+		name       string
+		from       model.LobbyStatus
+		to         model.LobbyStatus
+		roundsLeft bool
+		valid      bool
+	}{
+		{"first player joins", model.StatusCreated, model.StatusWaiting, false, true},
+		{"start", model.StatusWaiting, model.StatusInProgress, false, true},
+		{"start without players", model.StatusCreated, model.StatusInProgress, false, false},
+		{"accusation", model.StatusInProgress, model.StatusVoting, false, true},
+		{"failed vote", model.StatusVoting, model.StatusInProgress, false, true},
+		{"spy guess", model.StatusInProgress, model.StatusFinished, false, true},
+		{"restart finished match", model.StatusFinished, model.StatusInProgress, false, false},
+		{"next round", model.StatusFinished, model.StatusInProgress, true, true},
+		{"guess before start", model.StatusWaiting, model.StatusFinished, false, false},
+		{"abandon", model.StatusWaiting, model.StatusAbandoned, false, true},
+		{"end finished match", model.StatusFinished, model.StatusAbandoned, false, true},
+		{"revive abandoned", model.StatusAbandoned, model.StatusWaiting, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := model.TestLobby(t)
+			l.Status = tc.from
+			if tc.roundsLeft {
+				l.Rounds = 3
+			}
+
+			err := l.Transition(tc.to)
+			if tc.valid {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if l.Status != tc.to {
+					t.Fatalf("status = %s, want %s", l.Status, tc.to)
+				}
+				return
+			}
+
+			if !errors.Is(err, model.ErrIllegalTransition) {
+				t.Fatalf("error = %v, want ErrIllegalTransition", err)
+			}
+			if l.Status != tc.from {
+				t.Fatalf("status changed to %s on illegal transition", l.Status)
+			}
+		})
+	}
+}
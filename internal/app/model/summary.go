@@ -0,0 +1,14 @@
+package model
+
+// LobbySummary type describes an open lobby in the lobby browser
+type LobbySummary struct {
+	Token         string      `json:"token"`
+	Host          string      `json:"host"`
+	Status        LobbyStatus `json:"status"`
+	Players       int         `json:"players"`
+	MinPl         int         `json:"minpl"`
+	AmountPl      int         `json:"amountpl"`
+	AmountSpy     int         `json:"amountspy"`
+	RoundDuration int         `json:"roundduration"`
+	Rounds        int         `json:"rounds"`
+}
@@ -16,9 +16,14 @@ func TestLobby(t *testing.T) *Lobby {
 		Token:           "AAAAA",
 		Locations:       []string{"TestLoc1", "TestLoc2", "TestLoc3", "TestLoc4"},
 		CurrentLocation: "Test1",
+		MinPl:           3,
 		AmountPl:        5,
 		AmountSpy:       1,
 		SpyPlayers:      []string{"TestPlayer1"},
 		AllPlayers:      []string{"TestPlayer1", "TestPlayer2", "TestPlayer3", "TestPlayer4", "TestPlayer5"},
+		Status:          StatusWaiting,
+		RoundDuration:   480,
+		Rounds:          1,
+		Round:           1,
 	}
 }
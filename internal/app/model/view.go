@@ -0,0 +1,141 @@
+package model
+
+import "time"
+
+// Viewer kinds
+const (
+	ViewerSpy       = "spy"
+	ViewerPeaceful  = "peaceful"
+	ViewerPlayer    = "player"
+	ViewerSpectator = "spectator"
+)
+
+// LobbyView type is what a single viewer may see of a lobby
+type LobbyView struct {
+	Token           string            `json:"token"`
+	Host            string            `json:"host"`
+	Public          bool              `json:"public"`
+	Protected       bool              `json:"protected"`
+	Viewer          string            `json:"viewer"`
+	Locations       []string          `json:"locations"`
+	CurrentLocation string            `json:"currentloc,omitempty"`
+	Role            string            `json:"role,omitempty"`
+	MinPl           int               `json:"minpl"`
+	AmountPl        int               `json:"amountpl"`
+	AmountSpy       int               `json:"amountspy"`
+	SpyPlayers      []string          `json:"spyplayers,omitempty"`
+	AllPlayers      []string          `json:"allplayers"`
+	Spectators      []string          `json:"spectators"`
+	Banned          []string          `json:"banned,omitempty"`
+	Ready           map[string]bool   `json:"ready"`
+	Online          map[string]bool   `json:"online"`
+	Roles           map[string]string `json:"roles,omitempty"`
+	Status          LobbyStatus       `json:"status"`
+	Winner          string            `json:"winner,omitempty"`
+	Outcome         string            `json:"outcome,omitempty"`
+	Accuser         string            `json:"accuser,omitempty"`
+	Suspect         string            `json:"suspect,omitempty"`
+	Votes           map[string]bool   `json:"votes,omitempty"`
+	RoundDuration   int               `json:"roundduration"`
+	Clock
+	Rounds int            `json:"rounds"`
+	Round  int            `json:"round"`
+	Dealer string         `json:"dealer,omitempty"`
+	Scores map[string]int `json:"scores"`
+}
+
+// Clock is the state of the round clock, which every lobby response and
+// event carries
+type Clock struct {
+	Deadline *time.Time `json:"deadline,omitempty"`
+	Paused   bool       `json:"paused"`
+	TimeLeft int        `json:"timeleft"`
+}
+
+// Clock projects the round clock of the lobby
+func (l *Lobby) Clock() Clock {
+	return Clock{
+		Deadline: l.Deadline,
+		Paused:   l.Paused,
+		TimeLeft: l.TimeLeft,
+	}
+}
+
+// Revealed reports whether the secrets of the lobby's round are public,
+// which is the case once the round is over
+func (l *Lobby) Revealed() bool {
+	return l.Round > 0 && (l.Status == StatusFinished || l.Status == StatusAbandoned)
+}
+
+// ViewerKind returns how login takes part in the current round
+func (l *Lobby) ViewerKind(login string) string {
+	member := false
+	for _, player := range l.AllPlayers {
+		if player == login {
+			member = true
+			break
+		}
+	}
+
+	switch {
+	case !member:
+		return ViewerSpectator
+	case l.Status != StatusInProgress && l.Status != StatusVoting:
+		return ViewerPlayer
+	}
+
+	for _, spy := range l.SpyPlayers {
+		if spy == login {
+			return ViewerSpy
+		}
+	}
+	return ViewerPeaceful
+}
+
+// ViewFor projects the lobby for login. Spies never see the location,
+// peaceful players never see the spies, spectators and players outside of a
+// round see only public state, and everyone sees everything after the reveal.
+func (l *Lobby) ViewFor(login string) *LobbyView {
+	v := &LobbyView{
+		Token:         l.Token,
+		Host:          l.Host,
+		Public:        l.Public,
+		Protected:     l.Protected(),
+		Viewer:        l.ViewerKind(login),
+		Locations:     l.Locations,
+		MinPl:         l.MinPl,
+		AmountPl:      l.AmountPl,
+		AmountSpy:     l.AmountSpy,
+		AllPlayers:    l.AllPlayers,
+		Spectators:    l.Spectators,
+		Banned:        l.Banned,
+		Ready:         l.Ready,
+		Online:        l.Online,
+		Status:        l.Status,
+		Winner:        l.Winner,
+		Outcome:       l.Outcome,
+		Accuser:       l.Accuser,
+		Suspect:       l.Suspect,
+		Votes:         l.Votes,
+		RoundDuration: l.RoundDuration,
+		Clock:         l.Clock(),
+		Rounds:        l.Rounds,
+		Round:         l.Round,
+		Dealer:        l.Dealer,
+		Scores:        l.Scores,
+	}
+
+	if l.Revealed() {
+		v.CurrentLocation = l.CurrentLocation
+		v.SpyPlayers = l.SpyPlayers
+		v.Roles = l.Roles
+		return v
+	}
+
+	if v.Viewer == ViewerPeaceful {
+		v.CurrentLocation = l.CurrentLocation
+		v.Role = l.Roles[login]
+	}
+
+	return v
+}
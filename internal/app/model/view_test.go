@@ -0,0 +1,37 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+)
+
+func TestLobby_ViewFor(t *testing.T) {
+	l := model.TestLobby(t)
+	l.Status = model.StatusInProgress
+	l.Roles = map[string]string{"TestPlayer2": "Teller"}
+
+	spy := l.ViewFor("TestPlayer1")
+	if spy.Viewer != model.ViewerSpy || spy.CurrentLocation != "" || len(spy.SpyPlayers) != 0 {
+		t.Fatalf("spy view leaks secrets: %+v", spy)
+	}
+
+	peaceful := l.ViewFor("TestPlayer2")
+	if peaceful.Viewer != model.ViewerPeaceful || peaceful.CurrentLocation != l.CurrentLocation || peaceful.Role != "Teller" {
+		t.Fatalf("peaceful view misses own secrets: %+v", peaceful)
+	}
+	if len(peaceful.SpyPlayers) != 0 || len(peaceful.Roles) != 0 {
+		t.Fatalf("peaceful view leaks secrets: %+v", peaceful)
+	}
+
+	spectator := l.ViewFor("Stranger")
+	if spectator.Viewer != model.ViewerSpectator || spectator.CurrentLocation != "" || len(spectator.SpyPlayers) != 0 || spectator.Role != "" {
+		t.Fatalf("spectator view leaks secrets: %+v", spectator)
+	}
+
+	l.Status = model.StatusFinished
+	revealed := l.ViewFor("Stranger")
+	if revealed.CurrentLocation != l.CurrentLocation || len(revealed.SpyPlayers) != 1 || revealed.Roles["TestPlayer2"] != "Teller" {
+		t.Fatalf("finished round is not revealed: %+v", revealed)
+	}
+}
@@ -5,4 +5,12 @@ import "errors"
 var (
 	// ErrRecordNotFound error
 	ErrRecordNotFound = errors.New("Record not found")
+	// ErrLobbyFull error
+	ErrLobbyFull = errors.New("Lobby is full")
+	// ErrAlreadyJoined error
+	ErrAlreadyJoined = errors.New("User has already joined the lobby")
+	// ErrAlreadyVoted error
+	ErrAlreadyVoted = errors.New("User has already voted on this accusation")
+	// ErrBanned error
+	ErrBanned = errors.New("User is banned from the lobby")
 )
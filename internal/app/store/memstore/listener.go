@@ -0,0 +1,79 @@
+package memstore
+
+import (
+	"sync"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+)
+
+// Listener delivers the lobby changes of a Store like the Postgres
+// LISTEN/NOTIFY listener does
+type Listener struct {
+	mu     sync.Mutex
+	tokens map[string]bool
+
+	// sending guards notifications against being closed during a send
+	sending       sync.RWMutex
+	closed        bool
+	notifications chan *store.Notification
+}
+
+func newListener() *Listener {
+	return &Listener{
+		tokens:        make(map[string]bool),
+		notifications: make(chan *store.Notification, 64),
+	}
+}
+
+// Listen func
+func (l *Listener) Listen(token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens[token] = true
+	return nil
+}
+
+// Unlisten func
+func (l *Listener) Unlisten(token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.tokens, token)
+	return nil
+}
+
+// Notifications func
+func (l *Listener) Notifications() <-chan *store.Notification {
+	return l.notifications
+}
+
+// Close func
+func (l *Listener) Close() error {
+	l.sending.Lock()
+	defer l.sending.Unlock()
+
+	if !l.closed {
+		l.closed = true
+		close(l.notifications)
+	}
+	return nil
+}
+
+// notify delivers n if its lobby is listened to
+func (l *Listener) notify(n *store.Notification) {
+	l.mu.Lock()
+	listening := l.tokens[n.Token]
+	l.mu.Unlock()
+
+	if !listening {
+		return
+	}
+
+	l.sending.RLock()
+	defer l.sending.RUnlock()
+
+	if !l.closed {
+		l.notifications <- n
+	}
+}
@@ -0,0 +1,830 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+)
+
+// LobbyRepository struct
+type LobbyRepository struct {
+	store *Store
+}
+
+// Create func
+func (r *LobbyRepository) Create(ctx context.Context, l *model.Lobby) error {
+	r.store.lock()
+	defer r.store.unlock()
+
+	if _, ok := r.store.lobbies[l.Token]; ok {
+		return fmt.Errorf("Lobby %s already exists", l.Token)
+	}
+
+	stored := cloneLobby(l)
+	stored.CurrentLocation = ""
+	stored.Dealer = ""
+	stored.SpyPlayers = nil
+	stored.AllPlayers = []string{}
+	stored.Spectators = []string{}
+	stored.Banned = []string{}
+	stored.Scores = map[string]int{}
+	stored.Roles = map[string]string{}
+	stored.Ready = map[string]bool{}
+	stored.Online = map[string]bool{}
+	stored.Votes = map[string]bool{}
+	r.store.lobbies[l.Token] = stored
+
+	return nil
+}
+
+// FindByToken func
+func (r *LobbyRepository) FindByToken(ctx context.Context, token string) (*model.Lobby, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	stored, ok := r.store.lobbies[token]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	l := cloneLobby(stored)
+	l.CountDown(time.Now())
+
+	return l, nil
+}
+
+// FindForUpdate finds the lobby. Units of work hold the whole store locked,
+// so there is no row to lock.
+func (r *LobbyRepository) FindForUpdate(ctx context.Context, token string) (*model.Lobby, error) {
+	return r.FindByToken(ctx, token)
+}
+
+// FindOpen returns a page of the public lobbies that can still be joined,
+// fullest first so that new players fill up lobbies close to starting
+func (r *LobbyRepository) FindOpen(ctx context.Context, limit, offset int) ([]*model.LobbySummary, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	lobbies := []*model.LobbySummary{}
+	for _, l := range r.store.lobbies {
+		if !l.Public || l.Protected() || !l.Status.Joinable() || len(l.AllPlayers) >= l.AmountPl {
+			continue
+		}
+
+		lobbies = append(lobbies, &model.LobbySummary{
+			Token:         l.Token,
+			Host:          l.Host,
+			Status:        l.Status,
+			Players:       len(l.AllPlayers),
+			MinPl:         l.MinPl,
+			AmountPl:      l.AmountPl,
+			AmountSpy:     l.AmountSpy,
+			RoundDuration: l.RoundDuration,
+			Rounds:        l.Rounds,
+		})
+	}
+
+	sort.Slice(lobbies, func(i, j int) bool {
+		if lobbies[i].Players != lobbies[j].Players {
+			return lobbies[i].Players > lobbies[j].Players
+		}
+		return lobbies[i].Token < lobbies[j].Token
+	})
+
+	if offset >= len(lobbies) {
+		return []*model.LobbySummary{}, nil
+	}
+
+	lobbies = lobbies[offset:]
+	if len(lobbies) > limit {
+		lobbies = lobbies[:limit]
+	}
+
+	return lobbies, nil
+}
+
+// History returns the finished rounds of the lobby's match in the order they
+// were played
+func (r *LobbyRepository) History(ctx context.Context, token string) ([]*model.Round, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	rounds := []*model.Round{}
+	for _, round := range r.store.rounds[token] {
+		if round.EndedAt != nil {
+			rounds = append(rounds, cloneRound(round))
+		}
+	}
+
+	return rounds, nil
+}
+
+// CheckStatus func
+func (r *LobbyRepository) CheckStatus(ctx context.Context, token string) (model.LobbyStatus, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	stored, ok := r.store.lobbies[token]
+	if !ok {
+		return "", store.ErrRecordNotFound
+	}
+
+	return stored.Status, nil
+}
+
+// FindExpired returns the tokens of the lobbies whose running round clock ran
+// out by now
+func (r *LobbyRepository) FindExpired(ctx context.Context, now time.Time) ([]string, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	tokens := []string{}
+	for token, l := range r.store.lobbies {
+		if l.Status == model.StatusInProgress && !l.Paused && l.Deadline != nil && !l.Deadline.After(now) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+// ConnectUserToLobby adds login to the lobby players, enforcing the lobby
+// capacity and rejecting duplicate logins
+func (r *LobbyRepository) ConnectUserToLobby(ctx context.Context, l *model.Lobby, login string) error {
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if !stored.Status.Joinable() {
+			return fmt.Errorf("%w: players can't join a %s lobby", model.ErrIllegalTransition, stored.Status)
+		}
+
+		if contains(stored.Banned, login) {
+			return store.ErrBanned
+		}
+
+		if contains(stored.AllPlayers, login) {
+			return store.ErrAlreadyJoined
+		}
+
+		if len(stored.AllPlayers) >= stored.AmountPl {
+			return store.ErrLobbyFull
+		}
+
+		stored.Spectators = without(stored.Spectators, login)
+		stored.AllPlayers = append(stored.AllPlayers, login)
+		stored.Scores[login] = 0
+		stored.Ready[login] = false
+		stored.Online[login] = true
+		r.store.seen[presenceKey(stored.Token, login)] = time.Now()
+
+		if stored.Status == model.StatusCreated {
+			stored.Status = model.StatusWaiting
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyPlayerJoined, login)
+}
+
+// StartGame moves the lobby to its next round with the dealer, location,
+// spies and roles chosen for it, and starts the round clock
+func (r *LobbyRepository) StartGame(ctx context.Context, l *model.Lobby) error {
+	now := time.Now()
+	deadline := now.Add(time.Duration(l.RoundDuration) * time.Second)
+
+	if err := r.transition(l, model.StatusInProgress, func(stored *model.Lobby) {
+		stored.Round = l.Round + 1
+		stored.Dealer = l.Dealer
+		stored.CurrentLocation = l.CurrentLocation
+		stored.SpyPlayers = append([]string(nil), l.SpyPlayers...)
+		stored.Winner = ""
+		stored.Outcome = ""
+		stored.Accuser = ""
+		stored.Suspect = ""
+		stored.Deadline = &deadline
+		stored.Paused = false
+		stored.TimeLeft = l.RoundDuration
+		stored.Votes = map[string]bool{}
+
+		stored.Roles = map[string]string{}
+		for login, role := range l.Roles {
+			if contains(stored.AllPlayers, login) {
+				stored.Roles[login] = role
+			}
+		}
+
+		round := &model.Round{
+			Number:    stored.Round,
+			Dealer:    stored.Dealer,
+			Location:  stored.CurrentLocation,
+			Spies:     append([]string{}, stored.SpyPlayers...),
+			Roles:     map[string]string{},
+			Points:    map[string]int{},
+			StartedAt: now,
+		}
+		for login, role := range stored.Roles {
+			round.Roles[login] = role
+		}
+		r.store.rounds[l.Token] = append(r.store.rounds[l.Token], round)
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyGameStarted, "")
+}
+
+// WonForSpy ends the round in favour of the spies
+func (r *LobbyRepository) WonForSpy(ctx context.Context, l *model.Lobby, outcome string) (string, error) {
+
+	if err := r.finish(l, model.WinnerSpy, outcome); err != nil {
+		return "Spy won", err
+	}
+
+	return "Spy won", r.store.notify(l.Token, store.LobbyGameEnded, "")
+}
+
+// WonForPeaceful ends the round in favour of the peaceful players
+func (r *LobbyRepository) WonForPeaceful(ctx context.Context, l *model.Lobby, outcome string) (string, error) {
+
+	if err := r.finish(l, model.WinnerPeaceful, outcome); err != nil {
+		return "Peaceful won", err
+	}
+
+	return "Peaceful won", r.store.notify(l.Token, store.LobbyGameEnded, "")
+}
+
+// Accuse moves the lobby to the voting phase with accuser nominating suspect
+// and stops the round clock. The accuser's own vote is counted in favour of
+// the accusation. Once time is up the lobby is already voting and accusations
+// are taken without a further transition.
+func (r *LobbyRepository) Accuse(ctx context.Context, l *model.Lobby, accuser, suspect string) error {
+	l.CountDown(time.Now())
+
+	accuse := func(stored *model.Lobby) {
+		stored.Accuser = accuser
+		stored.Suspect = suspect
+		stored.Votes = map[string]bool{accuser: true}
+	}
+
+	var err error
+	if l.Status == model.StatusVoting && l.Suspect == "" {
+		err = r.update(l, func(stored *model.Lobby) error {
+			if stored.Status != model.StatusVoting || stored.Suspect != "" {
+				return fmt.Errorf("%w: another accusation is being voted on", model.ErrIllegalTransition)
+			}
+			accuse(stored)
+			return nil
+		})
+	} else {
+		timeleft := l.TimeLeft
+		err = r.transition(l, model.StatusVoting, func(stored *model.Lobby) {
+			accuse(stored)
+			stored.Deadline = nil
+			stored.Paused = true
+			stored.TimeLeft = timeleft
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyAccusation, accuser)
+}
+
+// Vote records the vote of login on the current accusation
+func (r *LobbyRepository) Vote(ctx context.Context, l *model.Lobby, login string, vote bool) error {
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if stored.Status != model.StatusVoting || stored.Suspect == "" {
+			return fmt.Errorf("%w: there is no vote in progress", model.ErrIllegalTransition)
+		}
+
+		if _, ok := stored.Votes[login]; ok {
+			return store.ErrAlreadyVoted
+		}
+
+		stored.Votes[login] = vote
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyVoteCast, login)
+}
+
+// DismissAccusation returns the lobby from a failed vote to the game and
+// restarts the round clock where the accusation stopped it
+func (r *LobbyRepository) DismissAccusation(ctx context.Context, l *model.Lobby) error {
+	if l.Status != model.StatusVoting {
+		return fmt.Errorf("%w: there is no vote in progress", model.ErrIllegalTransition)
+	}
+
+	suspect := l.Suspect
+	deadline := time.Now().Add(time.Duration(l.TimeLeft) * time.Second)
+
+	if err := r.transition(l, model.StatusInProgress, func(stored *model.Lobby) {
+		stored.Accuser = ""
+		stored.Suspect = ""
+		stored.Votes = map[string]bool{}
+		stored.Deadline = &deadline
+		stored.Paused = false
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyAcquittal, suspect)
+}
+
+// PauseTimer stops the round clock
+func (r *LobbyRepository) PauseTimer(ctx context.Context, l *model.Lobby) error {
+	if l.Status != model.StatusInProgress || l.Paused {
+		return fmt.Errorf("%w: the round clock is not running", model.ErrIllegalTransition)
+	}
+
+	l.CountDown(time.Now())
+	timeleft := l.TimeLeft
+
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if stored.Status != model.StatusInProgress || stored.Paused {
+			return fmt.Errorf("%w: the round clock is not running", model.ErrIllegalTransition)
+		}
+
+		stored.Deadline = nil
+		stored.Paused = true
+		stored.TimeLeft = timeleft
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyClockStopped, "")
+}
+
+// ResumeTimer restarts the round clock with the time it was paused with
+func (r *LobbyRepository) ResumeTimer(ctx context.Context, l *model.Lobby) error {
+	if l.Status != model.StatusInProgress || !l.Paused {
+		return fmt.Errorf("%w: the round clock is not paused", model.ErrIllegalTransition)
+	}
+
+	deadline := time.Now().Add(time.Duration(l.TimeLeft) * time.Second)
+
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if stored.Status != model.StatusInProgress || !stored.Paused {
+			return fmt.Errorf("%w: the round clock is not paused", model.ErrIllegalTransition)
+		}
+
+		stored.Deadline = &deadline
+		stored.Paused = false
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyClockStarted, "")
+}
+
+// TimeUp moves a lobby whose round clock ran out to the voting phase
+func (r *LobbyRepository) TimeUp(ctx context.Context, l *model.Lobby) error {
+	l.CountDown(time.Now())
+
+	if l.Paused || !l.TimeUp() {
+		return fmt.Errorf("%w: the round clock has not run out", model.ErrIllegalTransition)
+	}
+
+	if err := r.transition(l, model.StatusVoting, func(stored *model.Lobby) {
+		stored.Deadline = nil
+		stored.Paused = true
+		stored.TimeLeft = 0
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyTimeUp, "")
+}
+
+// UpdateSettings saves the match settings of a lobby that has not started yet
+func (r *LobbyRepository) UpdateSettings(ctx context.Context, l *model.Lobby) error {
+	settings := *l
+
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if !stored.Status.Joinable() || len(stored.AllPlayers) > settings.AmountPl {
+			return fmt.Errorf("%w: settings can only change before the game starts and must fit the joined players", model.ErrIllegalTransition)
+		}
+
+		stored.MinPl = settings.MinPl
+		stored.AmountPl = settings.AmountPl
+		stored.AmountSpy = settings.AmountSpy
+		stored.RoundDuration = settings.RoundDuration
+		stored.Rounds = settings.Rounds
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbySettings, "")
+}
+
+// TransferHost hands the lobby over to login, who must be one of its players
+func (r *LobbyRepository) TransferHost(ctx context.Context, l *model.Lobby, login string) error {
+	host := l.Host
+
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if stored.Host != host || !contains(stored.AllPlayers, login) {
+			return fmt.Errorf("%w: the host has changed or %s is not a player", model.ErrIllegalTransition, login)
+		}
+
+		stored.Host = login
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyHostChanged, login)
+}
+
+// LeaveLobby removes login from the lobby players, promoting the next player
+// to host if login was the host. Players can't leave in the middle of a round.
+func (r *LobbyRepository) LeaveLobby(ctx context.Context, l *model.Lobby, login string) error {
+	return r.removePlayer(l, login, false)
+}
+
+// KickPlayer removes login from the lobby players and bans them from rejoining
+func (r *LobbyRepository) KickPlayer(ctx context.Context, l *model.Lobby, login string) error {
+	return r.removePlayer(l, login, true)
+}
+
+// UnbanPlayer lets login join the lobby again
+func (r *LobbyRepository) UnbanPlayer(ctx context.Context, l *model.Lobby, login string) error {
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if !contains(stored.Banned, login) {
+			return store.ErrRecordNotFound
+		}
+
+		stored.Banned = without(stored.Banned, login)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyUnbanned, login)
+}
+
+// SetReady records whether login is ready for the game to start
+func (r *LobbyRepository) SetReady(ctx context.Context, l *model.Lobby, login string, ready bool) error {
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if !stored.Status.Joinable() || !contains(stored.AllPlayers, login) {
+			return fmt.Errorf("%w: players get ready only before the game starts", model.ErrIllegalTransition)
+		}
+
+		stored.Ready[login] = ready
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyReady, login)
+}
+
+// SetOnline records whether login is connected to the lobby, notifying the
+// other players only when it changes
+func (r *LobbyRepository) SetOnline(ctx context.Context, l *model.Lobby, login string, online bool) error {
+	changed := false
+
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if online {
+			r.store.seen[presenceKey(stored.Token, login)] = time.Now()
+		}
+		if contains(stored.AllPlayers, login) && stored.Online[login] != online {
+			stored.Online[login] = online
+			changed = true
+		}
+		return nil
+	}); err != nil && err != store.ErrRecordNotFound {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return r.store.notify(l.Token, store.LobbyPresence, login)
+}
+
+// Heartbeat records that the players are still connected to the lobby
+func (r *LobbyRepository) Heartbeat(ctx context.Context, token string, logins []string) error {
+	r.store.lock()
+	defer r.store.unlock()
+
+	now := time.Now()
+	for _, login := range logins {
+		r.store.seen[presenceKey(token, login)] = now
+	}
+
+	return nil
+}
+
+// ExpirePresence marks the players who haven't been seen for the grace
+// period as disconnected, notifying the other players of each
+func (r *LobbyRepository) ExpirePresence(ctx context.Context, grace time.Duration) error {
+	type member struct {
+		token, login string
+	}
+
+	r.store.lock()
+	cutoff := time.Now().Add(-grace)
+	away := []member{}
+	for token, l := range r.store.lobbies {
+		for _, login := range l.AllPlayers {
+			if l.Online[login] && r.store.seen[presenceKey(token, login)].Before(cutoff) {
+				l.Online[login] = false
+				away = append(away, member{token, login})
+			}
+		}
+	}
+	r.store.unlock()
+
+	for _, m := range away {
+		if err := r.store.notify(m.token, store.LobbyPresence, m.login); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WatchLobby adds login to the lobby spectators. Spectators are not counted
+// against the lobby capacity and are never dealt a role.
+func (r *LobbyRepository) WatchLobby(ctx context.Context, l *model.Lobby, login string) error {
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if stored.Status == model.StatusAbandoned {
+			return fmt.Errorf("%w: the lobby is closed", model.ErrIllegalTransition)
+		}
+
+		if contains(stored.AllPlayers, login) || contains(stored.Spectators, login) {
+			return store.ErrAlreadyJoined
+		}
+
+		if contains(stored.Banned, login) {
+			return store.ErrBanned
+		}
+
+		stored.Spectators = append(stored.Spectators, login)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyWatching, login)
+}
+
+// StopWatching removes login from the lobby spectators
+func (r *LobbyRepository) StopWatching(ctx context.Context, l *model.Lobby, login string) error {
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if !contains(stored.Spectators, login) {
+			return store.ErrRecordNotFound
+		}
+
+		stored.Spectators = without(stored.Spectators, login)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyUnwatched, login)
+}
+
+// EndGame abandons the lobby, ending the match for everyone
+func (r *LobbyRepository) EndGame(ctx context.Context, l *model.Lobby) error {
+	if err := r.transition(l, model.StatusAbandoned, func(stored *model.Lobby) {
+		stored.Deadline = nil
+		stored.Paused = false
+	}); err != nil {
+		return err
+	}
+
+	return r.store.notify(l.Token, store.LobbyClosed, "")
+}
+
+// removePlayer takes login out of the lobby, banning them if ban is set
+func (r *LobbyRepository) removePlayer(l *model.Lobby, login string, ban bool) error {
+	host := ""
+
+	if err := r.update(l, func(stored *model.Lobby) error {
+		if stored.Status == model.StatusInProgress || stored.Status == model.StatusVoting {
+			return fmt.Errorf("%w: players can't leave during a round", model.ErrIllegalTransition)
+		}
+
+		if !contains(stored.AllPlayers, login) {
+			return store.ErrRecordNotFound
+		}
+
+		if stored.IsHost(login) {
+			stored.Host = stored.Successor(login)
+			host = stored.Host
+		}
+
+		stored.AllPlayers = without(stored.AllPlayers, login)
+		delete(stored.Scores, login)
+		delete(stored.Roles, login)
+		delete(stored.Ready, login)
+		delete(stored.Online, login)
+
+		if ban && !contains(stored.Banned, login) {
+			stored.Banned = append(stored.Banned, login)
+			sort.Strings(stored.Banned)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	event := store.LobbyPlayerLeft
+	if ban {
+		event = store.LobbyPlayerKicked
+	}
+
+	if err := r.store.notify(l.Token, event, login); err != nil {
+		return err
+	}
+
+	if l.Host == host && host != login {
+		return r.store.notify(l.Token, store.LobbyHostChanged, host)
+	}
+
+	return nil
+}
+
+// finish ends the round with winner, stops the round clock and awards
+// the players their points for outcome
+func (r *LobbyRepository) finish(l *model.Lobby, winner, outcome string) error {
+	from := l.Outcome
+	l.Outcome = outcome
+	points := l.Points()
+	l.Outcome = from
+
+	return r.transition(l, model.StatusFinished, func(stored *model.Lobby) {
+		stored.Winner = winner
+		stored.Outcome = outcome
+		stored.Deadline = nil
+		stored.Paused = false
+		stored.TimeLeft = 0
+
+		for login, p := range points {
+			if contains(stored.AllPlayers, login) {
+				stored.Scores[login] += p
+			}
+		}
+
+		if rounds := r.store.rounds[l.Token]; len(rounds) > 0 {
+			now := time.Now()
+			round := rounds[len(rounds)-1]
+			round.Winner = winner
+			round.Outcome = outcome
+			round.EndedAt = &now
+			for _, login := range stored.AllPlayers {
+				round.Points[login] = points[login]
+			}
+		}
+	})
+}
+
+// transition moves the lobby to status to, changing it only if the lobby is
+// still in the status it was read with, and applies set to it
+func (r *LobbyRepository) transition(l *model.Lobby, to model.LobbyStatus, set func(*model.Lobby)) error {
+	from := l.Status
+	if err := l.CheckTransition(to); err != nil {
+		return err
+	}
+
+	return r.update(l, func(stored *model.Lobby) error {
+		if stored.Status != from {
+			return fmt.Errorf("%w: lobby is no longer %s", model.ErrIllegalTransition, from)
+		}
+
+		stored.Status = to
+		set(stored)
+		return nil
+	})
+}
+
+// update applies f to the stored lobby under the store lock and, if it
+// succeeds, refreshes l with the stored state
+func (r *LobbyRepository) update(l *model.Lobby, f func(*model.Lobby) error) error {
+	r.store.lock()
+	defer r.store.unlock()
+
+	stored, ok := r.store.lobbies[l.Token]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	changed := cloneLobby(stored)
+	if err := f(changed); err != nil {
+		return err
+	}
+
+	r.store.lobbies[l.Token] = changed
+
+	*l = *cloneLobby(changed)
+	l.CountDown(time.Now())
+
+	return nil
+}
+
+// cloneLobby returns a copy of l that shares no slices or maps with it
+func cloneLobby(l *model.Lobby) *model.Lobby {
+	c := *l
+
+	c.Locations = append([]string(nil), l.Locations...)
+	c.SpyPlayers = append([]string(nil), l.SpyPlayers...)
+	c.AllPlayers = append([]string{}, l.AllPlayers...)
+	c.Spectators = append([]string{}, l.Spectators...)
+	c.Banned = append([]string{}, l.Banned...)
+
+	c.Votes = make(map[string]bool, len(l.Votes))
+	for k, v := range l.Votes {
+		c.Votes[k] = v
+	}
+
+	c.Scores = make(map[string]int, len(l.Scores))
+	for k, v := range l.Scores {
+		c.Scores[k] = v
+	}
+
+	c.Roles = make(map[string]string, len(l.Roles))
+	for k, v := range l.Roles {
+		c.Roles[k] = v
+	}
+
+	c.Ready = make(map[string]bool, len(l.Ready))
+	for k, v := range l.Ready {
+		c.Ready[k] = v
+	}
+
+	c.Online = make(map[string]bool, len(l.Online))
+	for k, v := range l.Online {
+		c.Online[k] = v
+	}
+
+	c.LocationRoles = make(map[string][]string, len(l.LocationRoles))
+	for k, v := range l.LocationRoles {
+		c.LocationRoles[k] = append([]string(nil), v...)
+	}
+
+	if l.Deadline != nil {
+		deadline := *l.Deadline
+		c.Deadline = &deadline
+	}
+
+	return &c
+}
+
+// cloneRound returns a copy of round that shares no slices or maps with it
+func cloneRound(round *model.Round) *model.Round {
+	c := *round
+
+	c.Spies = append([]string{}, round.Spies...)
+
+	c.Roles = make(map[string]string, len(round.Roles))
+	for k, v := range round.Roles {
+		c.Roles[k] = v
+	}
+
+	c.Points = make(map[string]int, len(round.Points))
+	for k, v := range round.Points {
+		c.Points[k] = v
+	}
+
+	if round.EndedAt != nil {
+		endedAt := *round.EndedAt
+		c.EndedAt = &endedAt
+	}
+
+	return &c
+}
+
+// contains func
+func contains(a []string, x string) bool {
+	for _, n := range a {
+		if n == x {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns a copy of a with x removed
+func without(a []string, x string) []string {
+	b := make([]string, 0, len(a))
+	for _, n := range a {
+		if n != x {
+			b = append(b, n)
+		}
+	}
+	return b
+}
@@ -0,0 +1,102 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+)
+
+// PackRepository struct
+type PackRepository struct {
+	store *Store
+}
+
+// Create func
+func (r *PackRepository) Create(ctx context.Context, p *model.Pack) error {
+	r.store.lock()
+	defer r.store.unlock()
+
+	r.store.lastPack++
+	p.ID = r.store.lastPack
+	r.store.packs[p.ID] = clonePack(p)
+
+	return nil
+}
+
+// Find func
+func (r *PackRepository) Find(ctx context.Context, id int) (*model.Pack, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	p, ok := r.store.packs[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return clonePack(p), nil
+}
+
+// FindByOwner func
+func (r *PackRepository) FindByOwner(ctx context.Context, ownerID int) ([]*model.Pack, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	packs := []*model.Pack{}
+	for _, p := range r.store.packs {
+		if p.OwnerID == ownerID {
+			packs = append(packs, clonePack(p))
+		}
+	}
+
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].ID < packs[j].ID
+	})
+
+	return packs, nil
+}
+
+// Update replaces the name and locations of the pack
+func (r *PackRepository) Update(ctx context.Context, p *model.Pack) error {
+	r.store.lock()
+	defer r.store.unlock()
+
+	stored, ok := r.store.packs[p.ID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	updated := clonePack(p)
+	updated.OwnerID = stored.OwnerID
+	r.store.packs[p.ID] = updated
+
+	return nil
+}
+
+// Delete func
+func (r *PackRepository) Delete(ctx context.Context, id int) error {
+	r.store.lock()
+	defer r.store.unlock()
+
+	if _, ok := r.store.packs[id]; !ok {
+		return store.ErrRecordNotFound
+	}
+
+	delete(r.store.packs, id)
+
+	return nil
+}
+
+// clonePack func
+func clonePack(p *model.Pack) *model.Pack {
+	c := *p
+	c.Locations = make([]model.Location, len(p.Locations))
+	for i, location := range p.Locations {
+		c.Locations[i] = model.Location{
+			Name:  location.Name,
+			Roles: append([]string(nil), location.Roles...),
+		}
+	}
+	return &c
+}
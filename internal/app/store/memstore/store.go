@@ -0,0 +1,239 @@
+package memstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+)
+
+// Store keeps users, lobbies and packs in memory. It is safe for concurrent
+// use and is meant for tests and local development.
+type Store struct {
+	*data
+	// tx is the unit of work the store is bound to, nil outside WithTx
+	tx *unit
+
+	listener        *Listener
+	userRepository  *UserRepository
+	lobbyRepository *LobbyRepository
+	packRepository  *PackRepository
+}
+
+// data is shared by the store and the stores bound to its units of work
+type data struct {
+	mu      sync.Mutex
+	users   map[int]*model.User
+	lobbies map[string]*model.Lobby
+	rounds  map[string][]*model.Round
+	packs   map[int]*model.Pack
+	// seen is when each player was last known to be connected to a lobby,
+	// by presenceKey
+	seen     map[string]time.Time
+	lastUser int
+	lastPack int
+}
+
+// unit is a unit of work. It holds s.mu for its whole duration and buffers
+// the notifications until it succeeds.
+type unit struct {
+	notifications []*store.Notification
+}
+
+// New func
+func New() *Store {
+	return newStore(&data{
+		users:   make(map[int]*model.User),
+		lobbies: make(map[string]*model.Lobby),
+		rounds:  make(map[string][]*model.Round),
+		packs:   make(map[int]*model.Pack),
+		seen:    make(map[string]time.Time),
+	}, newListener(), nil)
+}
+
+// newStore func
+func newStore(d *data, listener *Listener, tx *unit) *Store {
+	s := &Store{
+		data:     d,
+		tx:       tx,
+		listener: listener,
+	}
+
+	// The repositories are created up front so that the accessors are safe
+	// to call from concurrent handlers
+	s.userRepository = &UserRepository{store: s}
+	s.lobbyRepository = &LobbyRepository{store: s}
+	s.packRepository = &PackRepository{store: s}
+
+	return s
+}
+
+// User func
+func (s *Store) User() store.UserRepository {
+	return s.userRepository
+}
+
+// Lobby func
+func (s *Store) Lobby() store.LobbyRepository {
+	return s.lobbyRepository
+}
+
+// Pack func
+func (s *Store) Pack() store.PackRepository {
+	return s.packRepository
+}
+
+// WithTx runs fn with the whole store locked, restoring its contents if fn
+// fails. Units of work are serialized, so the Store passed to fn must be the
+// only one fn uses.
+func (s *Store) WithTx(ctx context.Context, fn func(store.Store) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.tx != nil {
+		nested := newStore(s.data, s.listener, &unit{})
+		saved := s.snapshot()
+		committed := false
+		defer func() {
+			if !committed {
+				s.restore(saved)
+			}
+		}()
+
+		if err := fn(nested); err != nil {
+			return err
+		}
+		committed = true
+
+		s.tx.notifications = append(s.tx.notifications, nested.tx.notifications...)
+		return nil
+	}
+
+	tx := newStore(s.data, s.listener, &unit{})
+	s.mu.Lock()
+	saved := s.snapshot()
+
+	func() {
+		committed := false
+		defer func() {
+			if !committed {
+				s.restore(saved)
+			}
+			s.mu.Unlock()
+		}()
+
+		if err = fn(tx); err == nil {
+			err = ctx.Err()
+		}
+		committed = err == nil
+	}()
+
+	if err != nil {
+		return err
+	}
+
+	for _, n := range tx.tx.notifications {
+		s.listener.notify(n)
+	}
+
+	return nil
+}
+
+// Listener returns the listener receiving the lobby changes made through the store
+func (s *Store) Listener() *Listener {
+	return s.listener
+}
+
+// lock locks the store unless it is bound to a unit of work, which already
+// holds the lock
+func (s *Store) lock() {
+	if s.tx == nil {
+		s.mu.Lock()
+	}
+}
+
+// unlock func
+func (s *Store) unlock() {
+	if s.tx == nil {
+		s.mu.Unlock()
+	}
+}
+
+// notify sends a lobby change to the listener, or holds it back until the
+// unit of work the store is bound to succeeds. The change is numbered with
+// the lobby's next event sequence. Callers must not hold s.mu.
+func (s *Store) notify(token, event, login string) error {
+	n := &store.Notification{
+		Token: token,
+		Event: event,
+		Login: login,
+	}
+
+	s.lock()
+	if l, ok := s.lobbies[token]; ok {
+		l.EventSeq++
+		n.Seq = l.EventSeq
+	}
+	s.unlock()
+
+	if s.tx != nil {
+		s.tx.notifications = append(s.tx.notifications, n)
+		return nil
+	}
+
+	s.listener.notify(n)
+	return nil
+}
+
+// snapshot copies the contents of the store. Callers must hold s.mu.
+func (s *Store) snapshot() *data {
+	saved := &data{
+		users:    make(map[int]*model.User, len(s.users)),
+		lobbies:  make(map[string]*model.Lobby, len(s.lobbies)),
+		rounds:   make(map[string][]*model.Round, len(s.rounds)),
+		packs:    make(map[int]*model.Pack, len(s.packs)),
+		seen:     make(map[string]time.Time, len(s.seen)),
+		lastUser: s.lastUser,
+		lastPack: s.lastPack,
+	}
+
+	for id, u := range s.users {
+		stored := *u
+		saved.users[id] = &stored
+	}
+	for token, l := range s.lobbies {
+		saved.lobbies[token] = cloneLobby(l)
+	}
+	for token, rounds := range s.rounds {
+		for _, round := range rounds {
+			saved.rounds[token] = append(saved.rounds[token], cloneRound(round))
+		}
+	}
+	for id, p := range s.packs {
+		saved.packs[id] = clonePack(p)
+	}
+	for key, at := range s.seen {
+		saved.seen[key] = at
+	}
+
+	return saved
+}
+
+// restore puts back the contents saved by snapshot. Callers must hold s.mu.
+func (s *Store) restore(saved *data) {
+	s.users = saved.users
+	s.lobbies = saved.lobbies
+	s.rounds = saved.rounds
+	s.packs = saved.packs
+	s.seen = saved.seen
+	s.lastUser = saved.lastUser
+	s.lastPack = saved.lastPack
+}
+
+// presenceKey func
+func presenceKey(token, login string) string {
+	return token + "/" + login
+}
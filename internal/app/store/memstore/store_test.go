@@ -0,0 +1,112 @@
+package memstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store/memstore"
+)
+
+func TestStore_WithTx(t *testing.T) {
+	ctx := context.Background()
+	s := memstore.New()
+	defer s.Listener().Close()
+
+	if err := s.User().Create(ctx, &model.User{Login: "host", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lobby := &model.Lobby{Token: "abcdef", Host: "host", MinPl: 3, AmountPl: 8, AmountSpy: 1, Status: model.StatusCreated}
+	if err := s.Lobby().Create(ctx, lobby); err != nil {
+		t.Fatal(err)
+	}
+	s.Listener().Listen(lobby.Token)
+
+	failed := errors.New("failed")
+	err := s.WithTx(ctx, func(tx store.Store) error {
+		l, err := tx.Lobby().FindForUpdate(ctx, lobby.Token)
+		if err != nil {
+			return err
+		}
+		if err := tx.Lobby().ConnectUserToLobby(ctx, l, "host"); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+
+	l, err := s.Lobby().FindByToken(ctx, lobby.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.AllPlayers) != 0 || l.Status != model.StatusCreated {
+		t.Fatalf("failed unit of work was kept: %+v", l)
+	}
+
+	select {
+	case n := <-s.Listener().Notifications():
+		t.Fatalf("failed unit of work sent %+v", n)
+	default:
+	}
+
+	if err := s.WithTx(ctx, func(tx store.Store) error {
+		l, err := tx.Lobby().FindForUpdate(ctx, lobby.Token)
+		if err != nil {
+			return err
+		}
+		return tx.Lobby().ConnectUserToLobby(ctx, l, "host")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if l, _ := s.Lobby().FindByToken(ctx, lobby.Token); len(l.AllPlayers) != 1 {
+		t.Fatalf("unit of work was not kept: %+v", l)
+	}
+
+	select {
+	case n := <-s.Listener().Notifications():
+		if n.Event != store.LobbyPlayerJoined {
+			t.Fatalf("got %+v", n)
+		}
+	default:
+		t.Fatal("unit of work sent no notification")
+	}
+}
+
+func TestLobbyRepository_ExpirePresence(t *testing.T) {
+	ctx := context.Background()
+	s := memstore.New()
+	defer s.Listener().Close()
+
+	l := &model.Lobby{Token: "abcdef", Host: "host", MinPl: 3, AmountPl: 8, AmountSpy: 1, Status: model.StatusCreated}
+	if err := s.Lobby().Create(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Lobby().ConnectUserToLobby(ctx, l, "host"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Lobby().Heartbeat(ctx, l.Token, []string{"host"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Lobby().ExpirePresence(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if l, _ := s.Lobby().FindByToken(ctx, l.Token); !l.Online["host"] {
+		t.Fatal("a player seen within the grace period went offline")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := s.Lobby().ExpirePresence(ctx, 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if l, _ := s.Lobby().FindByToken(ctx, l.Token); l.Online["host"] {
+		t.Fatal("a player not seen for the grace period stayed online")
+	}
+}
@@ -0,0 +1,72 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+)
+
+// UserRepository struct
+type UserRepository struct {
+	store *Store
+}
+
+// Create func
+func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
+
+	if _, ok := u.Validate(); !ok {
+		return errors.New("Password must be longer")
+	}
+
+	if _, ok := u.EncryptPassword(); !ok {
+		return errors.New("Unable to encrypt password")
+	}
+
+	r.store.lock()
+	defer r.store.unlock()
+
+	for _, existing := range r.store.users {
+		if existing.Login == u.Login {
+			return errors.New("Login is already taken")
+		}
+	}
+
+	r.store.lastUser++
+	u.ID = r.store.lastUser
+
+	stored := *u
+	r.store.users[u.ID] = &stored
+
+	return nil
+}
+
+// Find func
+func (r *UserRepository) Find(ctx context.Context, id int) (*model.User, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	u, ok := r.store.users[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	found := *u
+	return &found, nil
+}
+
+// FindByLogin func
+func (r *UserRepository) FindByLogin(ctx context.Context, login string) (*model.User, error) {
+	r.store.lock()
+	defer r.store.unlock()
+
+	for _, u := range r.store.users {
+		if u.Login == login {
+			found := *u
+			return &found, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
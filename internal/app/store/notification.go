@@ -0,0 +1,42 @@
+package store
+
+// Lobby change events carried by notifications
+const (
+	LobbyPlayerJoined = "player_joined"
+	LobbyGameStarted  = "game_started"
+	LobbyGameEnded    = "game_ended"
+	LobbyAccusation   = "accusation"
+	LobbyVoteCast     = "vote_cast"
+	LobbyAcquittal    = "acquittal"
+	LobbyClockStopped = "clock_stopped"
+	LobbyClockStarted = "clock_started"
+	LobbyTimeUp       = "time_up"
+	LobbyPlayerLeft   = "player_left"
+	LobbyPlayerKicked = "player_kicked"
+	LobbyUnbanned     = "unbanned"
+	LobbyReady        = "ready"
+	LobbyPresence     = "presence"
+	LobbyWatching     = "watching"
+	LobbyUnwatched    = "unwatched"
+	LobbyHostChanged  = "host_changed"
+	LobbySettings     = "settings"
+	LobbyClosed       = "closed"
+)
+
+// Notification struct
+type Notification struct {
+	Token string `json:"token"`
+	Event string `json:"event"`
+	Login string `json:"login,omitempty"`
+	// Seq numbers the changes of the lobby in the order they were made. It is
+	// kept by the store, so every server sees the same number for a change.
+	Seq int64 `json:"seq"`
+}
+
+// Listener interface
+type Listener interface {
+	Listen(token string) error
+	Unlisten(token string) error
+	Notifications() <-chan *Notification
+	Close() error
+}
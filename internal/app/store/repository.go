@@ -1,22 +1,57 @@
 package store
 
-import "github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+import (
+	"context"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+)
 
 // UserRepository interface
 type UserRepository interface {
-	Create(*model.User) error
-	Find(int) (*model.User, error)
-	FindByLogin(string) (*model.User, error)
+	Create(context.Context, *model.User) error
+	Find(context.Context, int) (*model.User, error)
+	FindByLogin(context.Context, string) (*model.User, error)
+}
+
+// PackRepository interface
+type PackRepository interface {
+	Create(context.Context, *model.Pack) error
+	Find(context.Context, int) (*model.Pack, error)
+	FindByOwner(context.Context, int) ([]*model.Pack, error)
+	Update(context.Context, *model.Pack) error
+	Delete(context.Context, int) error
 }
 
 // LobbyRepository interface
 type LobbyRepository interface {
-	Create(*model.Lobby) error
-	FindByToken(string) (*model.Lobby, error)
-	CheckStatus(string) (string, error)
-	ConnectUserToLobby(*model.Lobby) error
-	StartGame(*model.Lobby) error
-	WonForSpy(*model.Lobby) (string, error)
-	WonForPeaceful(*model.Lobby) (string, error)
-	ChooseSpyPlayersInLobby(*model.Lobby) error
+	Create(context.Context, *model.Lobby) error
+	FindByToken(context.Context, string) (*model.Lobby, error)
+	FindForUpdate(context.Context, string) (*model.Lobby, error)
+	FindOpen(context.Context, int, int) ([]*model.LobbySummary, error)
+	CheckStatus(context.Context, string) (model.LobbyStatus, error)
+	FindExpired(context.Context, time.Time) ([]string, error)
+	History(context.Context, string) ([]*model.Round, error)
+	ConnectUserToLobby(context.Context, *model.Lobby, string) error
+	StartGame(context.Context, *model.Lobby) error
+	WonForSpy(context.Context, *model.Lobby, string) (string, error)
+	WonForPeaceful(context.Context, *model.Lobby, string) (string, error)
+	Accuse(context.Context, *model.Lobby, string, string) error
+	Vote(context.Context, *model.Lobby, string, bool) error
+	DismissAccusation(context.Context, *model.Lobby) error
+	PauseTimer(context.Context, *model.Lobby) error
+	ResumeTimer(context.Context, *model.Lobby) error
+	TimeUp(context.Context, *model.Lobby) error
+	UpdateSettings(context.Context, *model.Lobby) error
+	TransferHost(context.Context, *model.Lobby, string) error
+	LeaveLobby(context.Context, *model.Lobby, string) error
+	KickPlayer(context.Context, *model.Lobby, string) error
+	UnbanPlayer(context.Context, *model.Lobby, string) error
+	SetReady(context.Context, *model.Lobby, string, bool) error
+	SetOnline(context.Context, *model.Lobby, string, bool) error
+	Heartbeat(context.Context, string, []string) error
+	ExpirePresence(context.Context, time.Duration) error
+	WatchLobby(context.Context, *model.Lobby, string) error
+	StopWatching(context.Context, *model.Lobby, string) error
+	EndGame(context.Context, *model.Lobby) error
 }
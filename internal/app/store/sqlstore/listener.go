@@ -0,0 +1,83 @@
+package sqlstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnectInterval = 10 * time.Second
+	maxReconnectInterval = time.Minute
+)
+
+// Listener struct
+type Listener struct {
+	listener      *pq.Listener
+	notifications chan *store.Notification
+}
+
+// NewListener func
+func NewListener(databaseURL string) *Listener {
+	l := &Listener{
+		listener:      pq.NewListener(databaseURL, minReconnectInterval, maxReconnectInterval, nil),
+		notifications: make(chan *store.Notification, 64),
+	}
+
+	go l.run()
+
+	return l
+}
+
+// Listen func
+func (l *Listener) Listen(token string) error {
+	if err := l.listener.Listen(channelName(token)); err != nil && err != pq.ErrChannelAlreadyOpen {
+		return err
+	}
+
+	return nil
+}
+
+// Unlisten func
+func (l *Listener) Unlisten(token string) error {
+	if err := l.listener.Unlisten(channelName(token)); err != nil && err != pq.ErrChannelNotOpen {
+		return err
+	}
+
+	return nil
+}
+
+// Notifications func
+func (l *Listener) Notifications() <-chan *store.Notification {
+	return l.notifications
+}
+
+// Close func
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+func (l *Listener) run() {
+	defer close(l.notifications)
+
+	for n := range l.listener.Notify {
+		// pq sends nil after re-establishing a lost connection
+		if n == nil {
+			continue
+		}
+
+		notification := &store.Notification{}
+		if err := json.Unmarshal([]byte(n.Extra), notification); err != nil {
+			continue
+		}
+
+		l.notifications <- notification
+	}
+}
+
+// channelName returns the LISTEN/NOTIFY channel of a lobby
+func channelName(token string) string {
+	return "lobby_" + token
+}
@@ -1,93 +1,961 @@
 package sqlstore
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
 	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
 	"github.com/lib/pq"
 )
 
+// Kinds of lobby members
+const (
+	memberPlayer    = "player"
+	memberSpectator = "spectator"
+	memberBanned    = "banned"
+)
+
+// userID is the subquery selecting the id of the user whose login is the
+// parameter n
+func userID(n int) string {
+	return fmt.Sprintf("(SELECT id FROM users WHERE login = $%d)", n)
+}
+
 // LobbyRepository struct
 type LobbyRepository struct {
 	store *Store
 }
 
-// Create func
-func (r *LobbyRepository) Create(l *model.Lobby) error {
+// Create saves the lobby with its board of locations
+func (r *LobbyRepository) Create(ctx context.Context, l *model.Lobby) error {
 
-	return r.store.db.QueryRow("INSERT INTO lobbies (token, locations, currentlocation, amountpl, amountspy, spyplayers, allplayers, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING token",
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, "INSERT INTO lobbies (token, host_id, minpl, amountpl, amountspy, status, roundduration, rounds, public, password) VALUES ($1, "+userID(2)+", $3, $4, $5, $6, $7, $8, $9, $10) RETURNING token",
 		l.Token,
-		pq.Array(l.Locations),
-		l.CurrentLocation,
+		l.Host,
+		l.MinPl,
 		l.AmountPl,
 		l.AmountSpy,
-		pq.Array(l.SpyPlayers),
-		pq.Array(l.AllPlayers),
 		l.Status,
-	).Scan(&l.Token)
+		l.RoundDuration,
+		l.Rounds,
+		l.Public,
+		l.Password,
+	).Scan(&l.Token); err != nil {
+		return err
+	}
+
+	for i, name := range l.Locations {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO lobby_locations (token, position, name, roles) VALUES ($1, $2, $3, $4)",
+			l.Token,
+			i,
+			name,
+			pq.Array(l.LocationRoles[name]),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ConnectUserToLobby adds login to the lobby players in a single transaction,
+// enforcing the lobby capacity and rejecting duplicate logins. A spectator
+// joining the game stops watching it.
+func (r *LobbyRepository) ConnectUserToLobby(ctx context.Context, l *model.Lobby, login string) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status model.LobbyStatus
+	var amountpl int
+	if err := tx.QueryRowContext(ctx, "SELECT status, amountpl FROM lobbies WHERE token = $1 FOR UPDATE",
+		l.Token,
+	).Scan(&status, &amountpl); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrRecordNotFound
+		}
+		return err
+	}
+
+	if !status.Joinable() {
+		return fmt.Errorf("%w: players can't join a %s lobby", model.ErrIllegalTransition, status)
+	}
+
+	kind, err := memberKind(ctx, tx, l.Token, login)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case memberBanned:
+		return store.ErrBanned
+	case memberPlayer:
+		return store.ErrAlreadyJoined
+	}
+
+	if err := loadMembers(ctx, tx, l); err != nil {
+		return err
+	}
+
+	if len(l.AllPlayers) >= amountpl {
+		return store.ErrLobbyFull
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO lobby_members (token, user_id, kind, online) VALUES ($1, "+userID(2)+", $3, true) ON CONFLICT (token, user_id) DO UPDATE SET kind = $3, score = 0, ready = false, online = true, joined_at = now(), last_seen = now()",
+		l.Token,
+		login,
+		memberPlayer,
+	); err != nil {
+		return err
+	}
+
+	l.Status = status
+	if status == model.StatusCreated {
+		if err := r.transition(ctx, tx, l, model.StatusWaiting, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		l.Status = status
+		return err
+	}
+
+	l.Spectators = without(l.Spectators, login)
+	l.AllPlayers = append(l.AllPlayers, login)
+	l.Scores[login] = 0
+	l.Ready[login] = false
+	l.Online[login] = true
+
+	return r.notify(ctx, l.Token, store.LobbyPlayerJoined, login)
+}
+
+// StartGame moves the lobby to its next round with the dealer, location,
+// spies and roles chosen for it, and starts the round clock
+func (r *LobbyRepository) StartGame(ctx context.Context, l *model.Lobby) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	from := l.Status
+	deadline := time.Now().Add(time.Duration(l.RoundDuration) * time.Second)
+
+	if err := r.transition(ctx, tx, l, model.StatusInProgress,
+		"round = $4, accuser_id = NULL, suspect_id = NULL, deadline = $5, paused = false, timeleft = $6",
+		l.Round+1,
+		deadline,
+		l.RoundDuration,
+	); err != nil {
+		return err
+	}
+
+	if err := r.startRound(ctx, tx, l); err != nil {
+		l.Status = from
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		l.Status = from
+		return err
+	}
+
+	l.Round++
+	l.Winner = ""
+	l.Outcome = ""
+	l.Accuser = ""
+	l.Suspect = ""
+	l.Votes = map[string]bool{}
+	l.Deadline = &deadline
+	l.Paused = false
+	l.TimeLeft = l.RoundDuration
+
+	return r.notify(ctx, l.Token, store.LobbyGameStarted, "")
+}
+
+// startRound records the round being started with the roles dealt in it
+func (r *LobbyRepository) startRound(ctx context.Context, tx querier, l *model.Lobby) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM lobby_votes WHERE token = $1", l.Token); err != nil {
+		return err
+	}
+
+	var roundID int64
+	if err := tx.QueryRowContext(ctx, "INSERT INTO rounds (token, number, dealer_id, location) VALUES ($1, $2, "+userID(3)+", $4) RETURNING id",
+		l.Token,
+		l.Round+1,
+		l.Dealer,
+		l.CurrentLocation,
+	).Scan(&roundID); err != nil {
+		return err
+	}
+
+	dealt := make(map[string]bool)
+	for _, spy := range l.SpyPlayers {
+		dealt[spy] = true
+	}
+	for login := range l.Roles {
+		dealt[login] = true
+	}
+
+	for login := range dealt {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO round_roles (round_id, user_id, role, spy) VALUES ($1, "+userID(2)+", $3, $4)",
+			roundID,
+			login,
+			l.Roles[login],
+			contains(l.SpyPlayers, login),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WonForSpy ends the round in favour of the spies
+func (r *LobbyRepository) WonForSpy(ctx context.Context, l *model.Lobby, outcome string) (string, error) {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	if err := r.finish(ctx, l, model.WinnerSpy, outcome); err != nil {
+		return "Spy won", err
+	}
+
+	return "Spy won", r.notify(ctx, l.Token, store.LobbyGameEnded, "")
+}
+
+// WonForPeaceful ends the round in favour of the peaceful players
+func (r *LobbyRepository) WonForPeaceful(ctx context.Context, l *model.Lobby, outcome string) (string, error) {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	if err := r.finish(ctx, l, model.WinnerPeaceful, outcome); err != nil {
+		return "Peaceful won", err
+	}
+
+	return "Peaceful won", r.notify(ctx, l.Token, store.LobbyGameEnded, "")
+}
+
+// Accuse moves the lobby to the voting phase with accuser nominating suspect
+// and stops the round clock. The accuser's own vote is counted in favour of
+// the accusation. Once time is up the lobby is already voting and accusations
+// are taken without a further transition.
+func (r *LobbyRepository) Accuse(ctx context.Context, l *model.Lobby, accuser, suspect string) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	from := l.Status
+	l.CountDown(time.Now())
+
+	if from == model.StatusVoting && l.Suspect == "" {
+		result, err := tx.ExecContext(ctx, "UPDATE lobbies SET accuser_id = "+userID(1)+", suspect_id = "+userID(2)+" WHERE token = $3 AND status = $4 AND suspect_id IS NULL",
+			accuser,
+			suspect,
+			l.Token,
+			from,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("%w: another accusation is being voted on", model.ErrIllegalTransition)
+		}
+	} else if err := r.transition(ctx, tx, l, model.StatusVoting,
+		"accuser_id = "+userID(4)+", suspect_id = "+userID(5)+", deadline = NULL, paused = true, timeleft = $6",
+		accuser,
+		suspect,
+		l.TimeLeft,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM lobby_votes WHERE token = $1", l.Token); err != nil {
+		l.Status = from
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO lobby_votes (token, user_id, vote) VALUES ($1, "+userID(2)+", $3)",
+		l.Token,
+		accuser,
+		true,
+	); err != nil {
+		l.Status = from
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		l.Status = from
+		return err
+	}
+
+	l.Accuser = accuser
+	l.Suspect = suspect
+	l.Votes = map[string]bool{accuser: true}
+	l.Deadline = nil
+	l.Paused = true
+
+	return r.notify(ctx, l.Token, store.LobbyAccusation, accuser)
+}
+
+// Vote records the vote of login on the current accusation
+func (r *LobbyRepository) Vote(ctx context.Context, l *model.Lobby, login string, vote bool) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	result, err := r.store.q.ExecContext(ctx, "INSERT INTO lobby_votes (token, user_id, vote) SELECT $1, u.id, $3 FROM users u WHERE u.login = $2 AND EXISTS (SELECT 1 FROM lobbies WHERE token = $1 AND status = $4 AND suspect_id IS NOT NULL)",
+		l.Token,
+		login,
+		vote,
+		model.StatusVoting,
+	)
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == uniqueViolation {
+			return store.ErrAlreadyVoted
+		}
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: there is no vote in progress", model.ErrIllegalTransition)
+	}
+
+	votes, err := listVotes(ctx, r.store.q, l.Token)
+	if err != nil {
+		return err
+	}
+	l.Votes = votes
+
+	return r.notify(ctx, l.Token, store.LobbyVoteCast, login)
+}
+
+// DismissAccusation returns the lobby from a failed vote to the game and
+// restarts the round clock where the accusation stopped it
+func (r *LobbyRepository) DismissAccusation(ctx context.Context, l *model.Lobby) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	from := l.Status
+	if from != model.StatusVoting {
+		return fmt.Errorf("%w: there is no vote in progress", model.ErrIllegalTransition)
+	}
+
+	deadline := time.Now().Add(time.Duration(l.TimeLeft) * time.Second)
+
+	if err := r.transition(ctx, tx, l, model.StatusInProgress,
+		"accuser_id = NULL, suspect_id = NULL, deadline = $4, paused = false",
+		deadline,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM lobby_votes WHERE token = $1", l.Token); err != nil {
+		l.Status = from
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		l.Status = from
+		return err
+	}
+
+	suspect := l.Suspect
+	l.Accuser = ""
+	l.Suspect = ""
+	l.Votes = map[string]bool{}
+	l.Deadline = &deadline
+	l.Paused = false
+
+	return r.notify(ctx, l.Token, store.LobbyAcquittal, suspect)
+}
+
+// PauseTimer stops the round clock
+func (r *LobbyRepository) PauseTimer(ctx context.Context, l *model.Lobby) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	if l.Status != model.StatusInProgress || l.Paused {
+		return fmt.Errorf("%w: the round clock is not running", model.ErrIllegalTransition)
+	}
+
+	l.CountDown(time.Now())
+
+	result, err := r.store.q.ExecContext(ctx, "UPDATE lobbies SET deadline = NULL, paused = true, timeleft = $1 WHERE token = $2 AND status = $3 AND NOT paused",
+		l.TimeLeft,
+		l.Token,
+		model.StatusInProgress,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: the round clock is not running", model.ErrIllegalTransition)
+	}
+
+	l.Deadline = nil
+	l.Paused = true
+
+	return r.notify(ctx, l.Token, store.LobbyClockStopped, "")
+}
+
+// ResumeTimer restarts the round clock with the time it was paused with
+func (r *LobbyRepository) ResumeTimer(ctx context.Context, l *model.Lobby) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	if l.Status != model.StatusInProgress || !l.Paused {
+		return fmt.Errorf("%w: the round clock is not paused", model.ErrIllegalTransition)
+	}
+
+	deadline := time.Now().Add(time.Duration(l.TimeLeft) * time.Second)
+
+	result, err := r.store.q.ExecContext(ctx, "UPDATE lobbies SET deadline = $1, paused = false WHERE token = $2 AND status = $3 AND paused",
+		deadline,
+		l.Token,
+		model.StatusInProgress,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: the round clock is not paused", model.ErrIllegalTransition)
+	}
+
+	l.Deadline = &deadline
+	l.Paused = false
+
+	return r.notify(ctx, l.Token, store.LobbyClockStarted, "")
+}
+
+// TimeUp moves a lobby whose round clock ran out to the voting phase
+func (r *LobbyRepository) TimeUp(ctx context.Context, l *model.Lobby) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	now := time.Now()
+	l.CountDown(now)
+
+	if l.Paused || !l.TimeUp() {
+		return fmt.Errorf("%w: the round clock has not run out", model.ErrIllegalTransition)
+	}
+
+	if err := r.transition(ctx, r.store.q, l, model.StatusVoting,
+		"deadline = NULL, paused = true, timeleft = 0",
+	); err != nil {
+		return err
+	}
+
+	l.Deadline = nil
+	l.Paused = true
+	l.TimeLeft = 0
+
+	return r.notify(ctx, l.Token, store.LobbyTimeUp, "")
+}
+
+// SetReady records whether login is ready for the game to start
+func (r *LobbyRepository) SetReady(ctx context.Context, l *model.Lobby, login string, ready bool) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	result, err := r.store.q.ExecContext(ctx, "UPDATE lobby_members SET ready = $1 WHERE token = $2 AND user_id = "+userID(3)+" AND kind = $4 AND EXISTS (SELECT 1 FROM lobbies WHERE token = $2 AND status IN ($5, $6))",
+		ready,
+		l.Token,
+		login,
+		memberPlayer,
+		model.StatusCreated,
+		model.StatusWaiting,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: players get ready only before the game starts", model.ErrIllegalTransition)
+	}
+
+	l.Ready[login] = ready
+
+	return r.notify(ctx, l.Token, store.LobbyReady, login)
+}
+
+// SetOnline records whether login is connected to the lobby, notifying the
+// other players only when it changes
+func (r *LobbyRepository) SetOnline(ctx context.Context, l *model.Lobby, login string, online bool) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	result, err := r.store.q.ExecContext(ctx, "UPDATE lobby_members SET online = $1 WHERE token = $2 AND user_id = "+userID(3)+" AND kind = $4 AND online <> $1",
+		online,
+		l.Token,
+		login,
+		memberPlayer,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	l.Online[login] = online
+
+	return r.notify(ctx, l.Token, store.LobbyPresence, login)
+}
+
+// Heartbeat records that the players are still connected to the lobby
+func (r *LobbyRepository) Heartbeat(ctx context.Context, token string, logins []string) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	_, err := r.store.q.ExecContext(ctx, "UPDATE lobby_members SET last_seen = now() WHERE token = $1 AND user_id IN (SELECT id FROM users WHERE login = ANY ($2))",
+		token,
+		pq.Array(logins),
+	)
+	return err
+}
+
+// ExpirePresence marks the players who haven't been seen for the grace
+// period as disconnected, notifying the other players of each
+func (r *LobbyRepository) ExpirePresence(ctx context.Context, grace time.Duration) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	rows, err := r.store.q.QueryContext(ctx,
+		"UPDATE lobby_members m SET online = false FROM users u "+
+			"WHERE u.id = m.user_id AND m.kind = $1 AND m.online AND m.last_seen < now() - $2 * interval '1 millisecond' "+
+			"RETURNING m.token, u.login",
+		memberPlayer,
+		grace.Milliseconds(),
+	)
+	if err != nil {
+		return err
+	}
+
+	type member struct {
+		token, login string
+	}
+
+	away := []member{}
+	for rows.Next() {
+		m := member{}
+		if err := rows.Scan(&m.token, &m.login); err != nil {
+			rows.Close()
+			return err
+		}
+		away = append(away, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range away {
+		if err := r.notify(ctx, m.token, store.LobbyPresence, m.login); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WatchLobby adds login to the lobby spectators. Spectators are not counted
+// against the lobby capacity and are never dealt a role.
+func (r *LobbyRepository) WatchLobby(ctx context.Context, l *model.Lobby, login string) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status model.LobbyStatus
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM lobbies WHERE token = $1 FOR UPDATE",
+		l.Token,
+	).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrRecordNotFound
+		}
+		return err
+	}
+
+	if status == model.StatusAbandoned {
+		return fmt.Errorf("%w: the lobby is closed", model.ErrIllegalTransition)
+	}
+
+	kind, err := memberKind(ctx, tx, l.Token, login)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case memberPlayer, memberSpectator:
+		return store.ErrAlreadyJoined
+	case memberBanned:
+		return store.ErrBanned
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO lobby_members (token, user_id, kind) VALUES ($1, "+userID(2)+", $3)",
+		l.Token,
+		login,
+		memberSpectator,
+	); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == uniqueViolation {
+			return store.ErrAlreadyJoined
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	l.Status = status
+	l.Spectators = append(l.Spectators, login)
+
+	return r.notify(ctx, l.Token, store.LobbyWatching, login)
 }
 
-// ConnectUserToLobby func
-func (r *LobbyRepository) ConnectUserToLobby(l *model.Lobby) error {
+// StopWatching removes login from the lobby spectators
+func (r *LobbyRepository) StopWatching(ctx context.Context, l *model.Lobby, login string) error {
 
-	return r.store.db.QueryRow("UPDATE lobbies SET allplayers = $1 WHERE token = $2 RETURNING allplayers",
-		pq.Array(l.AllPlayers),
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	result, err := r.store.q.ExecContext(ctx, "DELETE FROM lobby_members WHERE token = $1 AND user_id = "+userID(2)+" AND kind = $3",
 		l.Token,
-	).Scan(pq.Array(&l.AllPlayers))
+		login,
+		memberSpectator,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	l.Spectators = without(l.Spectators, login)
+
+	return r.notify(ctx, l.Token, store.LobbyUnwatched, login)
 }
 
-// ChooseSpyPlayersInLobby func
-func (r *LobbyRepository) ChooseSpyPlayersInLobby(l *model.Lobby) error {
+// UpdateSettings saves the match settings of a lobby that has not started yet
+func (r *LobbyRepository) UpdateSettings(ctx context.Context, l *model.Lobby) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
 
-	return r.store.db.QueryRow("UPDATE lobbies SET spyplayers = $1 WHERE token = $2 RETURNING spyplayers",
-		pq.Array(l.SpyPlayers),
+	result, err := r.store.q.ExecContext(ctx, "UPDATE lobbies SET amountpl = $1, amountspy = $2, roundduration = $3, rounds = $4, minpl = $8 WHERE token = $5 AND status IN ($6, $7) AND (SELECT count(*) FROM lobby_members WHERE token = $5 AND kind = $9) <= $1",
+		l.AmountPl,
+		l.AmountSpy,
+		l.RoundDuration,
+		l.Rounds,
 		l.Token,
-	).Scan(pq.Array(&l.SpyPlayers))
+		model.StatusCreated,
+		model.StatusWaiting,
+		l.MinPl,
+		memberPlayer,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: settings can only change before the game starts and must fit the joined players", model.ErrIllegalTransition)
+	}
+
+	return r.notify(ctx, l.Token, store.LobbySettings, "")
 }
 
-// StartGame func
-func (r *LobbyRepository) StartGame(l *model.Lobby) error {
+// TransferHost hands the lobby over to login, who must be one of its players
+func (r *LobbyRepository) TransferHost(ctx context.Context, l *model.Lobby, login string) error {
 
-	return r.store.db.QueryRow("UPDATE lobbies SET status = $1 WHERE token = $2 RETURNING status",
-		"Started",
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	result, err := r.store.q.ExecContext(ctx, "UPDATE lobbies l SET host_id = m.user_id FROM lobby_members m WHERE l.token = $2 AND m.token = l.token AND m.user_id = "+userID(1)+" AND m.kind = $4 AND l.host_id IS NOT DISTINCT FROM "+userID(3),
+		login,
 		l.Token,
-	).Scan(&l.Token)
+		l.Host,
+		memberPlayer,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: the host has changed or %s is not a player", model.ErrIllegalTransition, login)
+	}
+
+	l.Host = login
+
+	return r.notify(ctx, l.Token, store.LobbyHostChanged, login)
 }
 
-// WonForSpy func
-func (r *LobbyRepository) WonForSpy(l *model.Lobby) (string, error) {
+// LeaveLobby removes login from the lobby players, promoting the next player
+// to host if login was the host. Players can't leave in the middle of a round.
+func (r *LobbyRepository) LeaveLobby(ctx context.Context, l *model.Lobby, login string) error {
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	return r.removePlayer(ctx, l, login, false)
+}
+
+// KickPlayer removes login from the lobby players and bans them from rejoining
+func (r *LobbyRepository) KickPlayer(ctx context.Context, l *model.Lobby, login string) error {
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	return r.removePlayer(ctx, l, login, true)
+}
 
-	return "Spy won", r.store.db.QueryRow("UPDATE lobbies SET status = $1 WHERE token = $2 RETURNING status",
-		"Spy won",
+// UnbanPlayer lets login join the lobby again
+func (r *LobbyRepository) UnbanPlayer(ctx context.Context, l *model.Lobby, login string) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	result, err := r.store.q.ExecContext(ctx, "DELETE FROM lobby_members WHERE token = $1 AND user_id = "+userID(2)+" AND kind = $3",
 		l.Token,
-	).Scan(&l.Status)
+		login,
+		memberBanned,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	l.Banned = without(l.Banned, login)
+
+	return r.notify(ctx, l.Token, store.LobbyUnbanned, login)
 }
 
-// WonForPeaceful func
-func (r *LobbyRepository) WonForPeaceful(l *model.Lobby) (string, error) {
+// removePlayer takes login out of the lobby, banning them if ban is set
+func (r *LobbyRepository) removePlayer(ctx context.Context, l *model.Lobby, login string, ban bool) error {
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	return "Peaceful won", r.store.db.QueryRow("UPDATE lobbies SET status = $1 WHERE token = $2 RETURNING status",
-		"Peaceful won",
+	var status model.LobbyStatus
+	var host string
+	if err := tx.QueryRowContext(ctx, "SELECT l.status, COALESCE(h.login, '') FROM lobbies l LEFT JOIN users h ON h.id = l.host_id WHERE l.token = $1 FOR UPDATE OF l",
 		l.Token,
-	).Scan(&l.Status)
+	).Scan(&status, &host); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrRecordNotFound
+		}
+		return err
+	}
+
+	if status == model.StatusInProgress || status == model.StatusVoting {
+		return fmt.Errorf("%w: players can't leave during a round", model.ErrIllegalTransition)
+	}
+
+	if err := loadMembers(ctx, tx, l); err != nil {
+		return err
+	}
+	l.Status = status
+	l.Host = host
+
+	var result sql.Result
+	if ban {
+		result, err = tx.ExecContext(ctx, "UPDATE lobby_members SET kind = $3, score = 0, ready = false, online = false WHERE token = $1 AND user_id = "+userID(2)+" AND kind = $4",
+			l.Token,
+			login,
+			memberBanned,
+			memberPlayer,
+		)
+	} else {
+		result, err = tx.ExecContext(ctx, "DELETE FROM lobby_members WHERE token = $1 AND user_id = "+userID(2)+" AND kind = $3",
+			l.Token,
+			login,
+			memberPlayer,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	successor := l.Host
+	if l.IsHost(login) {
+		successor = l.Successor(login)
+		if _, err := tx.ExecContext(ctx, "UPDATE lobbies SET host_id = "+userID(1)+" WHERE token = $2", successor, l.Token); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	l.AllPlayers = without(l.AllPlayers, login)
+	delete(l.Scores, login)
+	delete(l.Roles, login)
+	delete(l.Ready, login)
+	delete(l.Online, login)
+
+	event := store.LobbyPlayerLeft
+	if ban {
+		event = store.LobbyPlayerKicked
+		l.Banned = append(l.Banned, login)
+		sort.Strings(l.Banned)
+	}
+
+	if err := r.notify(ctx, l.Token, event, login); err != nil {
+		return err
+	}
+
+	if successor != l.Host {
+		l.Host = successor
+		return r.notify(ctx, l.Token, store.LobbyHostChanged, successor)
+	}
+
+	return nil
+}
+
+// EndGame abandons the lobby, ending the match for everyone
+func (r *LobbyRepository) EndGame(ctx context.Context, l *model.Lobby) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	if err := r.transition(ctx, r.store.q, l, model.StatusAbandoned,
+		"deadline = NULL, paused = false",
+	); err != nil {
+		return err
+	}
+
+	l.Deadline = nil
+	l.Paused = false
+
+	return r.notify(ctx, l.Token, store.LobbyClosed, "")
 }
 
 // FindByToken func
-func (r *LobbyRepository) FindByToken(token string) (*model.Lobby, error) {
+func (r *LobbyRepository) FindByToken(ctx context.Context, token string) (*model.Lobby, error) {
+	return r.find(ctx, token, "")
+}
+
+// FindForUpdate finds the lobby and locks its row until the unit of work the
+// store is bound to ends, so that concurrent changes to it wait their turn
+func (r *LobbyRepository) FindForUpdate(ctx context.Context, token string) (*model.Lobby, error) {
+	return r.find(ctx, token, " FOR UPDATE OF l")
+}
+
+// find loads the lobby, with lock appended to the query selecting its row
+func (r *LobbyRepository) find(ctx context.Context, token string, lock string) (*model.Lobby, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
 	l := &model.Lobby{}
-	if err := r.store.db.QueryRow(
-		"SELECT token, locations, currentlocation, amountpl, amountspy, spyplayers, allplayers, status FROM lobbies WHERE token = $1",
+	deadline := sql.NullTime{}
+	roundID := sql.NullInt64{}
+	if err := r.store.q.QueryRowContext(ctx,
+		"SELECT l.token, COALESCE(h.login, ''), l.minpl, l.amountpl, l.amountspy, l.status, COALESCE(a.login, ''), COALESCE(s.login, ''), l.roundduration, l.deadline, l.paused, l.timeleft, l.rounds, l.round, r.id, COALESCE(d.login, ''), COALESCE(r.location, ''), COALESCE(r.winner, ''), COALESCE(r.outcome, ''), l.public, l.password, l.event_seq "+
+			"FROM lobbies l "+
+			"LEFT JOIN users h ON h.id = l.host_id "+
+			"LEFT JOIN users a ON a.id = l.accuser_id "+
+			"LEFT JOIN users s ON s.id = l.suspect_id "+
+			"LEFT JOIN rounds r ON r.token = l.token AND r.number = l.round "+
+			"LEFT JOIN users d ON d.id = r.dealer_id "+
+			"WHERE l.token = $1"+lock,
 		token,
 	).Scan(
 		&l.Token,
-		pq.Array(&l.Locations),
-		&l.CurrentLocation,
+		&l.Host,
+		&l.MinPl,
 		&l.AmountPl,
 		&l.AmountSpy,
-		pq.Array(&l.SpyPlayers),
-		pq.Array(&l.AllPlayers),
 		&l.Status,
+		&l.Accuser,
+		&l.Suspect,
+		&l.RoundDuration,
+		&deadline,
+		&l.Paused,
+		&l.TimeLeft,
+		&l.Rounds,
+		&l.Round,
+		&roundID,
+		&l.Dealer,
+		&l.CurrentLocation,
+		&l.Winner,
+		&l.Outcome,
+		&l.Public,
+		&l.Password,
+		&l.EventSeq,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
@@ -95,13 +963,88 @@ func (r *LobbyRepository) FindByToken(token string) (*model.Lobby, error) {
 		return nil, err
 	}
 
+	if err := loadMembers(ctx, r.store.q, l); err != nil {
+		return nil, err
+	}
+
+	if err := loadBoard(ctx, r.store.q, l); err != nil {
+		return nil, err
+	}
+
+	if roundID.Valid {
+		if err := loadRoles(ctx, r.store.q, l, roundID.Int64); err != nil {
+			return nil, err
+		}
+	}
+
+	votes, err := listVotes(ctx, r.store.q, token)
+	if err != nil {
+		return nil, err
+	}
+	l.Votes = votes
+
+	if deadline.Valid {
+		l.Deadline = &deadline.Time
+	}
+	l.CountDown(time.Now())
+
 	return l, nil
 }
 
+// FindOpen returns a page of the public lobbies that can still be joined,
+// fullest first so that new players fill up lobbies close to starting
+func (r *LobbyRepository) FindOpen(ctx context.Context, limit, offset int) ([]*model.LobbySummary, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
+	rows, err := r.store.q.QueryContext(ctx,
+		"SELECT l.token, COALESCE(h.login, ''), l.status, l.minpl, l.amountpl, l.amountspy, l.roundduration, l.rounds, count(m.id) "+
+			"FROM lobbies l "+
+			"LEFT JOIN users h ON h.id = l.host_id "+
+			"LEFT JOIN lobby_members m ON m.token = l.token AND m.kind = $5 "+
+			"WHERE l.public AND l.password = '' AND l.status IN ($1, $2) "+
+			"GROUP BY l.token, h.login HAVING count(m.id) < l.amountpl "+
+			"ORDER BY count(m.id) DESC, l.token LIMIT $3 OFFSET $4",
+		model.StatusCreated,
+		model.StatusWaiting,
+		limit,
+		offset,
+		memberPlayer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lobbies := []*model.LobbySummary{}
+	for rows.Next() {
+		ls := &model.LobbySummary{}
+		if err := rows.Scan(
+			&ls.Token,
+			&ls.Host,
+			&ls.Status,
+			&ls.MinPl,
+			&ls.AmountPl,
+			&ls.AmountSpy,
+			&ls.RoundDuration,
+			&ls.Rounds,
+			&ls.Players,
+		); err != nil {
+			return nil, err
+		}
+		lobbies = append(lobbies, ls)
+	}
+
+	return lobbies, rows.Err()
+}
+
 // CheckStatus func
-func (r *LobbyRepository) CheckStatus(token string) (string, error) {
+func (r *LobbyRepository) CheckStatus(ctx context.Context, token string) (model.LobbyStatus, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
 	l := &model.Lobby{}
-	if err := r.store.db.QueryRow(
+	if err := r.store.q.QueryRowContext(ctx,
 		"SELECT status FROM lobbies WHERE token = $1",
 		token,
 	).Scan(
@@ -115,3 +1058,392 @@ func (r *LobbyRepository) CheckStatus(token string) (string, error) {
 
 	return l.Status, nil
 }
+
+// FindExpired returns the tokens of the lobbies whose running round clock ran
+// out by now
+func (r *LobbyRepository) FindExpired(ctx context.Context, now time.Time) ([]string, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
+	rows, err := r.store.q.QueryContext(ctx,
+		"SELECT token FROM lobbies WHERE status = $1 AND NOT paused AND deadline <= $2",
+		model.StatusInProgress,
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []string{}
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// History returns the finished rounds of the lobby's match in the order they
+// were played
+func (r *LobbyRepository) History(ctx context.Context, token string) ([]*model.Round, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
+	rows, err := r.store.q.QueryContext(ctx,
+		"SELECT r.id, r.number, COALESCE(d.login, ''), r.location, r.winner, r.outcome, r.started_at, r.ended_at FROM rounds r LEFT JOIN users d ON d.id = r.dealer_id WHERE r.token = $1 AND r.ended_at IS NOT NULL ORDER BY r.number",
+		token,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rounds := []*model.Round{}
+	byID := make(map[int64]*model.Round)
+	for rows.Next() {
+		var id int64
+		endedAt := sql.NullTime{}
+		round := &model.Round{
+			Spies:  []string{},
+			Roles:  map[string]string{},
+			Points: map[string]int{},
+		}
+		if err := rows.Scan(
+			&id,
+			&round.Number,
+			&round.Dealer,
+			&round.Location,
+			&round.Winner,
+			&round.Outcome,
+			&round.StartedAt,
+			&endedAt,
+		); err != nil {
+			return nil, err
+		}
+		if endedAt.Valid {
+			round.EndedAt = &endedAt.Time
+		}
+		rounds = append(rounds, round)
+		byID[id] = round
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	roles, err := r.store.q.QueryContext(ctx, "SELECT rr.round_id, u.login, rr.role, rr.spy FROM round_roles rr JOIN rounds r ON r.id = rr.round_id JOIN users u ON u.id = rr.user_id WHERE r.token = $1 ORDER BY u.login", token)
+	if err != nil {
+		return nil, err
+	}
+	defer roles.Close()
+
+	for roles.Next() {
+		var id int64
+		var login, role string
+		var spy bool
+		if err := roles.Scan(&id, &login, &role, &spy); err != nil {
+			return nil, err
+		}
+		round, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if spy {
+			round.Spies = append(round.Spies, login)
+		}
+		if role != "" {
+			round.Roles[login] = role
+		}
+	}
+	if err := roles.Err(); err != nil {
+		return nil, err
+	}
+
+	results, err := r.store.q.QueryContext(ctx, "SELECT rs.round_id, u.login, rs.points FROM round_results rs JOIN rounds r ON r.id = rs.round_id JOIN users u ON u.id = rs.user_id WHERE r.token = $1", token)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	for results.Next() {
+		var id int64
+		var login string
+		var points int
+		if err := results.Scan(&id, &login, &points); err != nil {
+			return nil, err
+		}
+		if round, ok := byID[id]; ok {
+			round.Points[login] = points
+		}
+	}
+
+	return rounds, results.Err()
+}
+
+// transition moves the lobby to status to, updating the row only if the lobby
+// is still in the status it was read with. set lists further assignments made
+// by the same update, with their parameters numbered from $4.
+func (r *LobbyRepository) transition(ctx context.Context, q querier, l *model.Lobby, to model.LobbyStatus, set string, args ...interface{}) error {
+	from := l.Status
+	if err := l.Transition(to); err != nil {
+		return err
+	}
+
+	query := "UPDATE lobbies SET status = $1"
+	if set != "" {
+		query += ", " + set
+	}
+	query += " WHERE token = $2 AND status = $3"
+
+	result, err := q.ExecContext(ctx, query, append([]interface{}{to, l.Token, from}, args...)...)
+	if err != nil {
+		l.Status = from
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		l.Status = from
+		return err
+	} else if n == 0 {
+		l.Status = from
+		return fmt.Errorf("%w: lobby is no longer %s", model.ErrIllegalTransition, from)
+	}
+
+	return nil
+}
+
+// finish ends the round with winner, stops the round clock and awards
+// the players their points for outcome, recording them in the round results
+func (r *LobbyRepository) finish(ctx context.Context, l *model.Lobby, winner, outcome string) error {
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	from := l.Status
+	if err := r.transition(ctx, tx, l, model.StatusFinished,
+		"deadline = NULL, paused = false, timeleft = 0",
+	); err != nil {
+		return err
+	}
+
+	var roundID int64
+	if err := tx.QueryRowContext(ctx, "UPDATE rounds SET winner = $1, outcome = $2, ended_at = now() WHERE token = $3 AND number = $4 RETURNING id",
+		winner,
+		outcome,
+		l.Token,
+		l.Round,
+	).Scan(&roundID); err != nil {
+		l.Status = from
+		return err
+	}
+
+	l.Outcome = outcome
+	points := l.Points()
+
+	for _, login := range l.AllPlayers {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO round_results (round_id, user_id, points) VALUES ($1, "+userID(2)+", $3)",
+			roundID,
+			login,
+			points[login],
+		); err != nil {
+			l.Status = from
+			return err
+		}
+
+		if points[login] == 0 {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE lobby_members SET score = score + $1 WHERE token = $2 AND user_id = "+userID(3),
+			points[login],
+			l.Token,
+			login,
+		); err != nil {
+			l.Status = from
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		l.Status = from
+		return err
+	}
+
+	for login, p := range points {
+		l.Scores[login] += p
+	}
+
+	l.Winner = winner
+	l.Deadline = nil
+	l.Paused = false
+	l.TimeLeft = 0
+
+	return nil
+}
+
+// notify publishes a lobby change on the lobby's LISTEN/NOTIFY channel. The
+// lobby's event sequence is bumped by the same statement, so the change is
+// numbered alike on every server and in the order the lobby row was locked.
+func (r *LobbyRepository) notify(ctx context.Context, token, event, login string) error {
+	_, err := r.store.q.ExecContext(ctx,
+		"WITH bumped AS (UPDATE lobbies SET event_seq = event_seq + 1 WHERE token = $1 RETURNING event_seq) "+
+			"SELECT pg_notify($2, json_build_object('token', $1::text, 'event', $3::text, 'login', $4::text, 'seq', event_seq)::text) FROM bumped",
+		token,
+		channelName(token),
+		event,
+		login,
+	)
+	return err
+}
+
+// memberKind returns how login takes part in the lobby, or "" if they don't
+func memberKind(ctx context.Context, q querier, token, login string) (string, error) {
+	var kind string
+	if err := q.QueryRowContext(ctx, "SELECT kind FROM lobby_members WHERE token = $1 AND user_id = "+userID(2),
+		token,
+		login,
+	).Scan(&kind); err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	return kind, nil
+}
+
+// loadMembers sets the lobby players, in the order they joined, with their
+// scores, ready flags and presence, along with its spectators and bans
+func loadMembers(ctx context.Context, q querier, l *model.Lobby) error {
+	rows, err := q.QueryContext(ctx, "SELECT u.login, m.kind, m.score, m.ready, m.online FROM lobby_members m JOIN users u ON u.id = m.user_id WHERE m.token = $1 ORDER BY m.joined_at, m.id", l.Token)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	l.AllPlayers = []string{}
+	l.Spectators = []string{}
+	l.Banned = []string{}
+	l.Scores = map[string]int{}
+	l.Ready = map[string]bool{}
+	l.Online = map[string]bool{}
+	for rows.Next() {
+		var login, kind string
+		var score int
+		var ready, online bool
+		if err := rows.Scan(&login, &kind, &score, &ready, &online); err != nil {
+			return err
+		}
+
+		switch kind {
+		case memberPlayer:
+			l.AllPlayers = append(l.AllPlayers, login)
+			l.Scores[login] = score
+			l.Ready[login] = ready
+			l.Online[login] = online
+		case memberSpectator:
+			l.Spectators = append(l.Spectators, login)
+		case memberBanned:
+			l.Banned = append(l.Banned, login)
+		}
+	}
+	sort.Strings(l.Banned)
+
+	return rows.Err()
+}
+
+// loadBoard sets the lobby locations and the roles at each of them
+func loadBoard(ctx context.Context, q querier, l *model.Lobby) error {
+	rows, err := q.QueryContext(ctx, "SELECT name, roles FROM lobby_locations WHERE token = $1 ORDER BY position", l.Token)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	l.Locations = []string{}
+	l.LocationRoles = map[string][]string{}
+	for rows.Next() {
+		var name string
+		var roles []string
+		if err := rows.Scan(&name, pq.Array(&roles)); err != nil {
+			return err
+		}
+		l.Locations = append(l.Locations, name)
+		l.LocationRoles[name] = roles
+	}
+
+	return rows.Err()
+}
+
+// loadRoles sets the spies of the round and the roles dealt to the players
+// still in the lobby
+func loadRoles(ctx context.Context, q querier, l *model.Lobby, roundID int64) error {
+	rows, err := q.QueryContext(ctx, "SELECT u.login, rr.role, rr.spy FROM round_roles rr JOIN users u ON u.id = rr.user_id WHERE rr.round_id = $1 ORDER BY u.login", roundID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	l.SpyPlayers = []string{}
+	l.Roles = map[string]string{}
+	for rows.Next() {
+		var login, role string
+		var spy bool
+		if err := rows.Scan(&login, &role, &spy); err != nil {
+			return err
+		}
+		if spy {
+			l.SpyPlayers = append(l.SpyPlayers, login)
+		}
+		if role != "" && contains(l.AllPlayers, login) {
+			l.Roles[login] = role
+		}
+	}
+
+	return rows.Err()
+}
+
+// listVotes returns the votes cast on the lobby's current accusation
+func listVotes(ctx context.Context, q querier, token string) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, "SELECT u.login, v.vote FROM lobby_votes v JOIN users u ON u.id = v.user_id WHERE v.token = $1", token)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := map[string]bool{}
+	for rows.Next() {
+		var login string
+		var vote bool
+		if err := rows.Scan(&login, &vote); err != nil {
+			return nil, err
+		}
+		votes[login] = vote
+	}
+
+	return votes, rows.Err()
+}
+
+// contains func
+func contains(a []string, x string) bool {
+	for _, n := range a {
+		if n == x {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns a copy of a with x removed
+func without(a []string, x string) []string {
+	b := make([]string, 0, len(a))
+	for _, n := range a {
+		if n != x {
+			b = append(b, n)
+		}
+	}
+	return b
+}
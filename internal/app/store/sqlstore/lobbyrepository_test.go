@@ -0,0 +1,183 @@
+package sqlstore_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store/sqlstore"
+)
+
+// testLobby creates the users and a lobby hosted by the first of them
+func testLobby(t *testing.T, s store.Store, amountpl int, logins ...string) *model.Lobby {
+	t.Helper()
+
+	ctx := context.Background()
+	for _, login := range logins {
+		if err := s.User().Create(ctx, &model.User{Login: login, Password: "password"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := &model.Lobby{
+		Token:         "abcdef",
+		Host:          logins[0],
+		Locations:     []string{"Beach", "Bank", "Hospital"},
+		MinPl:         3,
+		AmountPl:      amountpl,
+		AmountSpy:     1,
+		Status:        model.StatusCreated,
+		RoundDuration: 480,
+		Rounds:        1,
+	}
+	if err := s.Lobby().Create(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+func find(t *testing.T, s store.Store, token string) *model.Lobby {
+	t.Helper()
+
+	l, err := s.Lobby().FindByToken(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+func TestLobbyRepository_ConnectUserToLobby(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("users", "lobbies")
+
+	ctx := context.Background()
+	s := sqlstore.New(db)
+	lobby := testLobby(t, s, 3, "host", "p1", "p2", "p3")
+
+	if err := s.Lobby().ConnectUserToLobby(ctx, find(t, s, lobby.Token), "host"); err != nil {
+		t.Fatal(err)
+	}
+	if l := find(t, s, lobby.Token); l.Status != model.StatusWaiting {
+		t.Fatalf("got status %s after the first join, want %s", l.Status, model.StatusWaiting)
+	}
+
+	if err := s.Lobby().ConnectUserToLobby(ctx, find(t, s, lobby.Token), "host"); err != store.ErrAlreadyJoined {
+		t.Fatalf("got %v joining twice, want %v", err, store.ErrAlreadyJoined)
+	}
+
+	for _, login := range []string{"p1", "p2"} {
+		if err := s.Lobby().ConnectUserToLobby(ctx, find(t, s, lobby.Token), login); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Lobby().ConnectUserToLobby(ctx, find(t, s, lobby.Token), "p3"); err != store.ErrLobbyFull {
+		t.Fatalf("got %v joining a full lobby, want %v", err, store.ErrLobbyFull)
+	}
+
+	if err := s.Lobby().KickPlayer(ctx, find(t, s, lobby.Token), "p2"); err != nil {
+		t.Fatal(err)
+	}
+	l := find(t, s, lobby.Token)
+	if !reflect.DeepEqual(l.AllPlayers, []string{"host", "p1"}) || !reflect.DeepEqual(l.Banned, []string{"p2"}) {
+		t.Fatalf("got players %v and banned %v after the kick", l.AllPlayers, l.Banned)
+	}
+	if err := s.Lobby().ConnectUserToLobby(ctx, l, "p2"); err != store.ErrBanned {
+		t.Fatalf("got %v rejoining after a kick, want %v", err, store.ErrBanned)
+	}
+
+	if err := s.Lobby().UnbanPlayer(ctx, find(t, s, lobby.Token), "p2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Lobby().ConnectUserToLobby(ctx, find(t, s, lobby.Token), "p2"); err != nil {
+		t.Fatal(err)
+	}
+	if l := find(t, s, lobby.Token); !reflect.DeepEqual(l.AllPlayers, []string{"host", "p1", "p2"}) {
+		t.Fatalf("got players %v after the unban", l.AllPlayers)
+	}
+}
+
+func TestLobbyRepository_PlayRound(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("users", "lobbies")
+
+	ctx := context.Background()
+	s := sqlstore.New(db)
+	lobby := testLobby(t, s, 8, "host", "p1", "p2")
+
+	for _, login := range []string{"host", "p1", "p2"} {
+		if err := s.Lobby().ConnectUserToLobby(ctx, find(t, s, lobby.Token), login); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := find(t, s, lobby.Token)
+	stale := find(t, s, lobby.Token)
+	l.Dealer = "host"
+	l.CurrentLocation = "Beach"
+	l.SpyPlayers = []string{"p1"}
+	l.Roles = map[string]string{"host": "Lifeguard", "p2": "Tourist"}
+	if err := s.Lobby().StartGame(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Lobby().StartGame(ctx, stale); !errors.Is(err, model.ErrIllegalTransition) {
+		t.Fatalf("got %v starting a lobby read before it started, want %v", err, model.ErrIllegalTransition)
+	}
+
+	l = find(t, s, lobby.Token)
+	if l.Status != model.StatusInProgress || l.Round != 1 || l.Deadline == nil {
+		t.Fatalf("got status %s, round %d and deadline %v after the start", l.Status, l.Round, l.Deadline)
+	}
+	if l.Dealer != "host" || l.CurrentLocation != "Beach" || !reflect.DeepEqual(l.SpyPlayers, []string{"p1"}) || l.Roles["p2"] != "Tourist" {
+		t.Fatalf("the dealt round was not kept: %+v", l)
+	}
+
+	if err := s.Lobby().Accuse(ctx, l, "host", "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Lobby().Vote(ctx, l, "host", false); err != store.ErrAlreadyVoted {
+		t.Fatalf("got %v voting twice, want %v", err, store.ErrAlreadyVoted)
+	}
+	if err := s.Lobby().Vote(ctx, l, "p2", true); err != nil {
+		t.Fatal(err)
+	}
+
+	l = find(t, s, lobby.Token)
+	if l.Status != model.StatusVoting || l.Suspect != "p1" || !reflect.DeepEqual(l.Votes, map[string]bool{"host": true, "p2": true}) {
+		t.Fatalf("got status %s, suspect %s and votes %v during the vote", l.Status, l.Suspect, l.Votes)
+	}
+
+	if _, err := s.Lobby().WonForPeaceful(ctx, l, model.OutcomeSpyCaught); err != nil {
+		t.Fatal(err)
+	}
+
+	l = find(t, s, lobby.Token)
+	want := map[string]int{"host": 2, "p1": 0, "p2": 1}
+	if l.Status != model.StatusFinished || l.Winner != model.WinnerPeaceful || !reflect.DeepEqual(l.Scores, want) {
+		t.Fatalf("got status %s, winner %s and scores %v after the round", l.Status, l.Winner, l.Scores)
+	}
+
+	history, err := s.Lobby().History(ctx, lobby.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("got %d rounds in the history, want 1", len(history))
+	}
+
+	round := history[0]
+	if round.Number != 1 || round.Dealer != "host" || round.Location != "Beach" || round.Winner != model.WinnerPeaceful || round.Outcome != model.OutcomeSpyCaught || round.EndedAt == nil {
+		t.Fatalf("got round %+v", round)
+	}
+	if !reflect.DeepEqual(round.Spies, []string{"p1"}) || !reflect.DeepEqual(round.Roles, l.Roles) {
+		t.Fatalf("got spies %v and roles %v in the history", round.Spies, round.Roles)
+	}
+	if !reflect.DeepEqual(round.Points, map[string]int{"host": 2, "p2": 1, "p1": 0}) {
+		t.Fatalf("got points %v in the history", round.Points)
+	}
+}
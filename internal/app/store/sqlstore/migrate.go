@@ -0,0 +1,265 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationName matches the migration file names, e.g. 0001_create_users.up.sql
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change with the scripts applying and
+// reverting it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus type
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrations returns the migrations embedded in the binary ordered by version
+func Migrations() ([]*Migration, error) {
+	files, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		match := migrationName.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("Unexpected migration file %s", file.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("Migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+
+		script, err := migrationFiles.ReadFile(path.Join("migrations", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("Migration %d %s needs both an up and a down script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("Migration %d is missing", i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Migrator applies the embedded migrations to a database, recording the
+// applied versions in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+// NewMigrator func
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest returns the version of the newest migration
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version returns the version the database schema is at, 0 if no migration
+// has been applied
+func (m *Migrator) Version() (int, error) {
+	if err := m.init(); err != nil {
+		return 0, err
+	}
+
+	return currentVersion(m.db)
+}
+
+// Status lists every migration with the time it was applied at, if it was
+func (m *Migrator) Status() ([]*MigrationStatus, error) {
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := &MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := applied[migration.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// Up applies every migration that has not been applied yet
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down reverts the last applied migration
+func (m *Migrator) Down() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	return m.To(version - 1)
+}
+
+// To applies or reverts migrations one at a time until the schema is at version
+func (m *Migrator) To(version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("Unknown schema version %d, the latest is %d", version, m.Latest())
+	}
+
+	if err := m.init(); err != nil {
+		return err
+	}
+
+	for {
+		done, err := m.step(version)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// step applies or reverts a single migration towards version, reporting
+// whether the schema is already there. The schema_migrations table is locked
+// for the step so that servers starting together don't migrate twice.
+func (m *Migrator) step(version int) (bool, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("LOCK TABLE schema_migrations IN EXCLUSIVE MODE"); err != nil {
+		return false, err
+	}
+
+	current, err := currentVersion(tx)
+	if err != nil {
+		return false, err
+	}
+
+	if current > m.Latest() {
+		return false, fmt.Errorf("The schema is at version %d, newer than this build knows of", current)
+	}
+
+	switch {
+	case current == version:
+		return true, nil
+	case current < version:
+		migration := m.migrations[current]
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return false, fmt.Errorf("Migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name); err != nil {
+			return false, err
+		}
+	default:
+		migration := m.migrations[current-1]
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return false, fmt.Errorf("Migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+			return false, err
+		}
+	}
+
+	return false, tx.Commit()
+}
+
+// init creates the schema_migrations table
+func (m *Migrator) init() error {
+	_, err := m.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name varchar NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())")
+	return err
+}
+
+// currentVersion func
+func currentVersion(q querier) (int, error) {
+	var version int
+	if err := q.QueryRowContext(context.Background(), "SELECT COALESCE(max(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
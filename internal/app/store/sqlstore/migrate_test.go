@@ -0,0 +1,113 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store/sqlstore"
+	"github.com/lib/pq"
+)
+
+// baselineSchema is the schema of the tables as deployed before the
+// migrations, with a lobby two players have joined
+var baselineSchema = []string{
+	"CREATE TABLE users (id bigserial PRIMARY KEY, login varchar NOT NULL UNIQUE, password varchar NOT NULL)",
+	"CREATE TABLE lobbies (token varchar PRIMARY KEY, locations text[], currentlocation varchar, amountpl integer, amountspy integer, spyplayers text[], allplayers text[], status varchar)",
+	"INSERT INTO users (login, password) VALUES ('host', 'password'), ('p1', 'password')",
+	"INSERT INTO lobbies VALUES ('abcdef', '{Beach,Bank}', '', 8, 1, '{}', '{host,p1}', 'Created')",
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := sqlstore.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("no migrations are embedded")
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d has version %d", i+1, m.Version)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Fatalf("migration %d %s has an empty script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrator_Baseline(t *testing.T) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		t.Skipf("Postgres is not available: %v", err)
+	}
+
+	// A single connection keeps the search path, so the migrations run in a
+	// schema of their own
+	db.SetMaxOpenConns(1)
+	for _, query := range []string{"DROP SCHEMA IF EXISTS migrate_test CASCADE", "CREATE SCHEMA migrate_test", "SET search_path TO migrate_test"} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP SCHEMA migrate_test CASCADE")
+
+	for _, query := range baselineSchema {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrator, err := sqlstore.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func() {
+		t.Helper()
+
+		l, err := sqlstore.New(db).Lobby().FindByToken(context.Background(), "abcdef")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Status != model.StatusWaiting || !reflect.DeepEqual(l.AllPlayers, []string{"host", "p1"}) || !reflect.DeepEqual(l.Locations, []string{"Beach", "Bank"}) {
+			t.Fatalf("got status %s, players %v and locations %v", l.Status, l.AllPlayers, l.Locations)
+		}
+	}
+
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	check()
+
+	if err := migrator.To(0); err != nil {
+		t.Fatal(err)
+	}
+
+	var users int
+	if err := db.QueryRow("SELECT count(*) FROM users").Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	var players []string
+	if err := db.QueryRow("SELECT allplayers FROM lobbies WHERE token = 'abcdef'").Scan(pq.Array(&players)); err != nil {
+		t.Fatal(err)
+	}
+	if users != 2 || !reflect.DeepEqual(players, []string{"host", "p1"}) {
+		t.Fatalf("got %d users and players %v after migrating down", users, players)
+	}
+
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
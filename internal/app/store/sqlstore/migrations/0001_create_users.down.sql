@@ -0,0 +1,2 @@
+-- The users table predates the migrations and 0001 only adopts it, so it is
+-- kept: dropping it would delete every account. There is nothing to undo.
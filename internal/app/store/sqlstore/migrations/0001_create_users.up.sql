@@ -0,0 +1,6 @@
+-- The users table predates the migrations, so it is only created if missing
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    login varchar NOT NULL UNIQUE,
+    password varchar NOT NULL
+);
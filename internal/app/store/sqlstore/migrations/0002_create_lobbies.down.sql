@@ -0,0 +1,30 @@
+-- The lobbies table predates the migrations and 0002 only adopts it, so it is
+-- kept with its baseline columns: dropping it would delete every lobby. Only
+-- the columns, constraints and index added by 0002 are undone.
+DROP INDEX IF EXISTS lobbies_open_idx;
+
+ALTER TABLE lobbies
+    ALTER COLUMN status DROP NOT NULL,
+    ALTER COLUMN status DROP DEFAULT,
+    ALTER COLUMN currentlocation DROP NOT NULL,
+    ALTER COLUMN currentlocation DROP DEFAULT,
+    ALTER COLUMN amountspy DROP NOT NULL,
+    ALTER COLUMN amountspy DROP DEFAULT;
+
+ALTER TABLE lobbies
+    DROP COLUMN IF EXISTS host,
+    DROP COLUMN IF EXISTS public,
+    DROP COLUMN IF EXISTS password,
+    DROP COLUMN IF EXISTS locationroles,
+    DROP COLUMN IF EXISTS minpl,
+    DROP COLUMN IF EXISTS winner,
+    DROP COLUMN IF EXISTS outcome,
+    DROP COLUMN IF EXISTS accuser,
+    DROP COLUMN IF EXISTS suspect,
+    DROP COLUMN IF EXISTS roundduration,
+    DROP COLUMN IF EXISTS deadline,
+    DROP COLUMN IF EXISTS paused,
+    DROP COLUMN IF EXISTS timeleft,
+    DROP COLUMN IF EXISTS rounds,
+    DROP COLUMN IF EXISTS round,
+    DROP COLUMN IF EXISTS dealer;
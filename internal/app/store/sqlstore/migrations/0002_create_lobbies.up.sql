@@ -0,0 +1,52 @@
+-- The lobbies table predates the migrations: it is only created if missing
+-- and the columns added since the baseline are added to it. The baseline
+-- allplayers column is kept for 0003 to move into its own table.
+CREATE TABLE IF NOT EXISTS lobbies (
+    token varchar PRIMARY KEY
+);
+
+ALTER TABLE lobbies
+    ADD COLUMN IF NOT EXISTS host varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS public boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS password varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS locations text[],
+    ADD COLUMN IF NOT EXISTS locationroles jsonb,
+    ADD COLUMN IF NOT EXISTS currentlocation varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS minpl integer NOT NULL DEFAULT 3,
+    ADD COLUMN IF NOT EXISTS amountpl integer NOT NULL DEFAULT 8,
+    ADD COLUMN IF NOT EXISTS amountspy integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS spyplayers text[],
+    ADD COLUMN IF NOT EXISTS allplayers text[],
+    ADD COLUMN IF NOT EXISTS status varchar NOT NULL DEFAULT 'Created',
+    ADD COLUMN IF NOT EXISTS winner varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS outcome varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS accuser varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS suspect varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS roundduration integer NOT NULL DEFAULT 480,
+    ADD COLUMN IF NOT EXISTS deadline timestamptz,
+    ADD COLUMN IF NOT EXISTS paused boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS timeleft integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rounds integer NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS round integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dealer varchar NOT NULL DEFAULT '';
+
+-- The baseline columns may be nullable
+UPDATE lobbies SET status = 'Created' WHERE status IS NULL;
+UPDATE lobbies SET currentlocation = '' WHERE currentlocation IS NULL;
+UPDATE lobbies SET amountspy = 0 WHERE amountspy IS NULL;
+
+ALTER TABLE lobbies
+    ALTER COLUMN status SET NOT NULL,
+    ALTER COLUMN status SET DEFAULT 'Created',
+    ALTER COLUMN currentlocation SET NOT NULL,
+    ALTER COLUMN currentlocation SET DEFAULT '',
+    ALTER COLUMN amountspy SET NOT NULL,
+    ALTER COLUMN amountspy SET DEFAULT 0;
+
+-- Baseline lobbies that were started have played their first round, and the
+-- ones that were won record the winner of it
+UPDATE lobbies SET status = 'InProgress', round = 1 WHERE status = 'Started';
+UPDATE lobbies SET status = 'Finished', round = 1, winner = 'spy' WHERE status = 'Spy won';
+UPDATE lobbies SET status = 'Finished', round = 1, winner = 'peaceful' WHERE status = 'Peaceful won';
+
+CREATE INDEX IF NOT EXISTS lobbies_open_idx ON lobbies (status) WHERE public AND password = '';
@@ -0,0 +1,8 @@
+ALTER TABLE lobbies ADD COLUMN allplayers text[];
+
+UPDATE lobbies l SET allplayers = ARRAY(SELECT p.login FROM lobby_players p WHERE p.token = l.token ORDER BY p.id);
+
+DROP TABLE lobby_votes;
+DROP TABLE lobby_bans;
+DROP TABLE lobby_spectators;
+DROP TABLE lobby_players;
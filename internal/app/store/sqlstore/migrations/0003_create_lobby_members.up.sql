@@ -0,0 +1,46 @@
+CREATE TABLE lobby_players (
+    id bigserial PRIMARY KEY,
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    login varchar NOT NULL,
+    score integer NOT NULL DEFAULT 0,
+    role varchar NOT NULL DEFAULT '',
+    ready boolean NOT NULL DEFAULT false,
+    online boolean NOT NULL DEFAULT false,
+    UNIQUE (token, login)
+);
+
+CREATE TABLE lobby_spectators (
+    id bigserial PRIMARY KEY,
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    login varchar NOT NULL,
+    UNIQUE (token, login)
+);
+
+CREATE TABLE lobby_bans (
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    login varchar NOT NULL,
+    PRIMARY KEY (token, login)
+);
+
+CREATE TABLE lobby_votes (
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    login varchar NOT NULL,
+    vote boolean NOT NULL,
+    PRIMARY KEY (token, login)
+);
+
+-- Players of baseline lobbies move from the allplayers column, keeping the
+-- order they joined in
+INSERT INTO lobby_players (token, login)
+SELECT l.token, p.login
+FROM lobbies l, unnest(l.allplayers) WITH ORDINALITY AS p (login, position)
+WHERE p.login IS NOT NULL AND p.login <> ''
+ORDER BY l.token, p.position
+ON CONFLICT DO NOTHING;
+
+-- Baseline lobbies stayed Created after players joined, which the lobby model
+-- calls Waiting; left as Created they could never be started
+UPDATE lobbies l SET status = 'Waiting'
+WHERE l.status = 'Created' AND EXISTS (SELECT 1 FROM lobby_players p WHERE p.token = l.token);
+
+ALTER TABLE lobbies DROP COLUMN allplayers;
@@ -0,0 +1,2 @@
+DROP TABLE pack_locations;
+DROP TABLE packs;
@@ -0,0 +1,15 @@
+CREATE TABLE packs (
+    id bigserial PRIMARY KEY,
+    owner_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name varchar NOT NULL
+);
+
+CREATE INDEX packs_owner_id_idx ON packs (owner_id);
+
+CREATE TABLE pack_locations (
+    pack_id bigint NOT NULL REFERENCES packs (id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    name varchar NOT NULL,
+    roles text[],
+    PRIMARY KEY (pack_id, position)
+);
@@ -0,0 +1,91 @@
+ALTER TABLE lobbies
+    ADD COLUMN host varchar NOT NULL DEFAULT '',
+    ADD COLUMN accuser varchar NOT NULL DEFAULT '',
+    ADD COLUMN suspect varchar NOT NULL DEFAULT '',
+    ADD COLUMN locations text[],
+    ADD COLUMN locationroles jsonb,
+    ADD COLUMN currentlocation varchar NOT NULL DEFAULT '',
+    ADD COLUMN spyplayers text[],
+    ADD COLUMN winner varchar NOT NULL DEFAULT '',
+    ADD COLUMN outcome varchar NOT NULL DEFAULT '',
+    ADD COLUMN dealer varchar NOT NULL DEFAULT '';
+
+UPDATE lobbies l SET host = u.login FROM users u WHERE u.id = l.host_id;
+UPDATE lobbies l SET accuser = u.login FROM users u WHERE u.id = l.accuser_id;
+UPDATE lobbies l SET suspect = u.login FROM users u WHERE u.id = l.suspect_id;
+
+UPDATE lobbies l SET
+    locations = ARRAY(SELECT loc.name FROM lobby_locations loc WHERE loc.token = l.token ORDER BY loc.position),
+    locationroles = (SELECT jsonb_object_agg(loc.name, COALESCE(to_jsonb(loc.roles), '[]'::jsonb)) FROM lobby_locations loc WHERE loc.token = l.token);
+
+UPDATE lobbies l SET
+    currentlocation = r.location,
+    winner = r.winner,
+    outcome = r.outcome,
+    dealer = COALESCE((SELECT u.login FROM users u WHERE u.id = r.dealer_id), ''),
+    spyplayers = ARRAY(SELECT u.login FROM round_roles rr JOIN users u ON u.id = rr.user_id WHERE rr.round_id = r.id AND rr.spy)
+FROM rounds r
+WHERE r.token = l.token AND r.number = l.round;
+
+ALTER TABLE lobbies
+    DROP COLUMN host_id,
+    DROP COLUMN accuser_id,
+    DROP COLUMN suspect_id;
+
+ALTER TABLE lobby_votes ADD COLUMN login varchar;
+UPDATE lobby_votes v SET login = u.login FROM users u WHERE u.id = v.user_id;
+ALTER TABLE lobby_votes DROP CONSTRAINT lobby_votes_pkey;
+ALTER TABLE lobby_votes DROP COLUMN user_id;
+ALTER TABLE lobby_votes ALTER COLUMN login SET NOT NULL;
+ALTER TABLE lobby_votes ADD PRIMARY KEY (token, login);
+
+CREATE TABLE lobby_players (
+    id bigserial PRIMARY KEY,
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    login varchar NOT NULL,
+    score integer NOT NULL DEFAULT 0,
+    role varchar NOT NULL DEFAULT '',
+    ready boolean NOT NULL DEFAULT false,
+    online boolean NOT NULL DEFAULT false,
+    UNIQUE (token, login)
+);
+
+CREATE TABLE lobby_spectators (
+    id bigserial PRIMARY KEY,
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    login varchar NOT NULL,
+    UNIQUE (token, login)
+);
+
+CREATE TABLE lobby_bans (
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    login varchar NOT NULL,
+    PRIMARY KEY (token, login)
+);
+
+INSERT INTO lobby_players (token, login, score, role, ready, online)
+SELECT m.token, u.login, m.score, COALESCE(rr.role, ''), m.ready, m.online
+FROM lobby_members m
+JOIN users u ON u.id = m.user_id
+JOIN lobbies l ON l.token = m.token
+LEFT JOIN rounds r ON r.token = m.token AND r.number = l.round
+LEFT JOIN round_roles rr ON rr.round_id = r.id AND rr.user_id = m.user_id
+WHERE m.kind = 'player'
+ORDER BY m.joined_at, m.id;
+
+INSERT INTO lobby_spectators (token, login)
+SELECT m.token, u.login
+FROM lobby_members m JOIN users u ON u.id = m.user_id
+WHERE m.kind = 'spectator'
+ORDER BY m.joined_at, m.id;
+
+INSERT INTO lobby_bans (token, login)
+SELECT m.token, u.login
+FROM lobby_members m JOIN users u ON u.id = m.user_id
+WHERE m.kind = 'banned';
+
+DROP TABLE round_results;
+DROP TABLE round_roles;
+DROP TABLE rounds;
+DROP TABLE lobby_locations;
+DROP TABLE lobby_members;
@@ -0,0 +1,133 @@
+-- Lobby members, boards and rounds move out of the lobbies row into their own
+-- tables, linked to users by id
+
+CREATE TABLE lobby_members (
+    id bigserial PRIMARY KEY,
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind varchar NOT NULL CHECK (kind IN ('player', 'spectator', 'banned')),
+    score integer NOT NULL DEFAULT 0,
+    ready boolean NOT NULL DEFAULT false,
+    online boolean NOT NULL DEFAULT false,
+    joined_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (token, user_id)
+);
+
+CREATE INDEX lobby_members_user_id_idx ON lobby_members (user_id);
+
+CREATE TABLE lobby_locations (
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    position integer NOT NULL,
+    name varchar NOT NULL,
+    roles text[],
+    PRIMARY KEY (token, position)
+);
+
+CREATE TABLE rounds (
+    id bigserial PRIMARY KEY,
+    token varchar NOT NULL REFERENCES lobbies (token) ON DELETE CASCADE,
+    number integer NOT NULL,
+    dealer_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    location varchar NOT NULL,
+    winner varchar NOT NULL DEFAULT '',
+    outcome varchar NOT NULL DEFAULT '',
+    started_at timestamptz NOT NULL DEFAULT now(),
+    ended_at timestamptz,
+    UNIQUE (token, number)
+);
+
+CREATE TABLE round_roles (
+    round_id bigint NOT NULL REFERENCES rounds (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role varchar NOT NULL DEFAULT '',
+    spy boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (round_id, user_id)
+);
+
+CREATE TABLE round_results (
+    round_id bigint NOT NULL REFERENCES rounds (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    points integer NOT NULL,
+    PRIMARY KEY (round_id, user_id)
+);
+
+-- Members keep the order they joined in
+INSERT INTO lobby_members (token, user_id, kind, score, ready, online)
+SELECT p.token, u.id, 'player', p.score, p.ready, p.online
+FROM lobby_players p JOIN users u ON u.login = p.login
+ORDER BY p.id;
+
+INSERT INTO lobby_members (token, user_id, kind)
+SELECT s.token, u.id, 'spectator'
+FROM lobby_spectators s JOIN users u ON u.login = s.login
+ORDER BY s.id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO lobby_members (token, user_id, kind)
+SELECT b.token, u.id, 'banned'
+FROM lobby_bans b JOIN users u ON u.login = b.login
+ON CONFLICT DO NOTHING;
+
+INSERT INTO lobby_locations (token, position, name, roles)
+SELECT l.token, loc.position, loc.name,
+    CASE WHEN jsonb_typeof(l.locationroles -> loc.name) = 'array'
+        THEN ARRAY(SELECT jsonb_array_elements_text(l.locationroles -> loc.name)) END
+FROM lobbies l, unnest(l.locations) WITH ORDINALITY AS loc (name, position);
+
+-- Only the current round of each lobby is known
+INSERT INTO rounds (token, number, dealer_id, location, winner, outcome, ended_at)
+SELECT l.token, l.round, u.id, l.currentlocation, l.winner, l.outcome,
+    CASE WHEN l.status IN ('Finished', 'Abandoned') THEN now() END
+FROM lobbies l LEFT JOIN users u ON u.login = l.dealer
+WHERE l.round > 0;
+
+INSERT INTO round_roles (round_id, user_id, role)
+SELECT r.id, u.id, p.role
+FROM lobby_players p
+JOIN lobbies l ON l.token = p.token
+JOIN rounds r ON r.token = l.token AND r.number = l.round
+JOIN users u ON u.login = p.login
+WHERE p.role <> '';
+
+-- The spies of the current round come from the spyplayers array, which
+-- baseline lobbies filled without dealing roles
+INSERT INTO round_roles (round_id, user_id, spy)
+SELECT DISTINCT r.id, u.id, true
+FROM lobbies l
+JOIN rounds r ON r.token = l.token AND r.number = l.round
+CROSS JOIN unnest(l.spyplayers) AS spy (login)
+JOIN users u ON u.login = spy.login
+ON CONFLICT (round_id, user_id) DO UPDATE SET spy = true;
+
+ALTER TABLE lobby_votes ADD COLUMN user_id bigint REFERENCES users (id) ON DELETE CASCADE;
+UPDATE lobby_votes v SET user_id = u.id FROM users u WHERE u.login = v.login;
+DELETE FROM lobby_votes WHERE user_id IS NULL;
+ALTER TABLE lobby_votes DROP CONSTRAINT lobby_votes_pkey;
+ALTER TABLE lobby_votes DROP COLUMN login;
+ALTER TABLE lobby_votes ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE lobby_votes ADD PRIMARY KEY (token, user_id);
+
+ALTER TABLE lobbies
+    ADD COLUMN host_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    ADD COLUMN accuser_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    ADD COLUMN suspect_id bigint REFERENCES users (id) ON DELETE SET NULL;
+
+UPDATE lobbies l SET host_id = u.id FROM users u WHERE u.login = l.host;
+UPDATE lobbies l SET accuser_id = u.id FROM users u WHERE u.login = l.accuser;
+UPDATE lobbies l SET suspect_id = u.id FROM users u WHERE u.login = l.suspect;
+
+ALTER TABLE lobbies
+    DROP COLUMN host,
+    DROP COLUMN accuser,
+    DROP COLUMN suspect,
+    DROP COLUMN locations,
+    DROP COLUMN locationroles,
+    DROP COLUMN currentlocation,
+    DROP COLUMN spyplayers,
+    DROP COLUMN winner,
+    DROP COLUMN outcome,
+    DROP COLUMN dealer;
+
+DROP TABLE lobby_bans;
+DROP TABLE lobby_spectators;
+DROP TABLE lobby_players;
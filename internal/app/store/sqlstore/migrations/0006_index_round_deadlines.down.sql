@@ -0,0 +1 @@
+DROP INDEX lobbies_deadline_idx;
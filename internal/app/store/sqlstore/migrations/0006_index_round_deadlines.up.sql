@@ -0,0 +1,2 @@
+-- Lets the servers find the rounds whose clock ran out
+CREATE INDEX lobbies_deadline_idx ON lobbies (deadline) WHERE status = 'InProgress' AND NOT paused;
@@ -0,0 +1 @@
+ALTER TABLE lobby_members DROP COLUMN last_seen;
@@ -0,0 +1,5 @@
+-- Presence is kept in the database so that every server sees the connections
+-- held by the others
+ALTER TABLE lobby_members ADD COLUMN last_seen timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX lobby_members_last_seen_idx ON lobby_members (last_seen) WHERE online;
@@ -0,0 +1 @@
+ALTER TABLE lobbies DROP COLUMN event_seq;
@@ -0,0 +1,3 @@
+-- Lobby changes are numbered in the database so that every server gives an
+-- event the same id and clients can resume their stream on any of them
+ALTER TABLE lobbies ADD COLUMN event_seq bigint NOT NULL DEFAULT 0;
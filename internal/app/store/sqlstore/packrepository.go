@@ -0,0 +1,192 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/model"
+	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
+	"github.com/lib/pq"
+)
+
+// PackRepository struct
+type PackRepository struct {
+	store *Store
+}
+
+// Create func
+func (r *PackRepository) Create(ctx context.Context, p *model.Pack) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, "INSERT INTO packs (owner_id, name) VALUES ($1, $2) RETURNING id",
+		p.OwnerID,
+		p.Name,
+	).Scan(&p.ID); err != nil {
+		return err
+	}
+
+	if err := insertPackLocations(ctx, tx, p); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Find func
+func (r *PackRepository) Find(ctx context.Context, id int) (*model.Pack, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
+	p := &model.Pack{}
+	if err := r.store.q.QueryRowContext(ctx,
+		"SELECT id, owner_id, name FROM packs WHERE id = $1",
+		id,
+	).Scan(
+		&p.ID,
+		&p.OwnerID,
+		&p.Name,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	locations, err := listPackLocations(ctx, r.store.q, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.Locations = locations
+
+	return p, nil
+}
+
+// FindByOwner func
+func (r *PackRepository) FindByOwner(ctx context.Context, ownerID int) ([]*model.Pack, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
+	rows, err := r.store.q.QueryContext(ctx, "SELECT id, owner_id, name FROM packs WHERE owner_id = $1 ORDER BY id", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	packs := []*model.Pack{}
+	for rows.Next() {
+		p := &model.Pack{}
+		if err := rows.Scan(&p.ID, &p.OwnerID, &p.Name); err != nil {
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range packs {
+		locations, err := listPackLocations(ctx, r.store.q, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		p.Locations = locations
+	}
+
+	return packs, nil
+}
+
+// Update replaces the name and locations of the pack
+func (r *PackRepository) Update(ctx context.Context, p *model.Pack) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	tx, err := r.store.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE packs SET name = $1 WHERE id = $2", p.Name, p.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pack_locations WHERE pack_id = $1", p.ID); err != nil {
+		return err
+	}
+
+	if err := insertPackLocations(ctx, tx, p); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete func
+func (r *PackRepository) Delete(ctx context.Context, id int) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
+
+	result, err := r.store.q.ExecContext(ctx, "DELETE FROM packs WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// insertPackLocations func
+func insertPackLocations(ctx context.Context, q querier, p *model.Pack) error {
+	for i, location := range p.Locations {
+		if _, err := q.ExecContext(ctx, "INSERT INTO pack_locations (pack_id, position, name, roles) VALUES ($1, $2, $3, $4)",
+			p.ID,
+			i,
+			location.Name,
+			pq.Array(location.Roles),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listPackLocations func
+func listPackLocations(ctx context.Context, q querier, packID int) ([]model.Location, error) {
+	rows, err := q.QueryContext(ctx, "SELECT name, roles FROM pack_locations WHERE pack_id = $1 ORDER BY position", packID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []model.Location{}
+	for rows.Next() {
+		location := model.Location{}
+		if err := rows.Scan(&location.Name, pq.Array(&location.Roles)); err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+
+	return locations, rows.Err()
+}
@@ -1,24 +1,61 @@
 package sqlstore
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/TOIFLMSC/spyfall-web-backend/internal/app/store"
 
 	_ "github.com/lib/pq"
 )
 
+// uniqueViolation is the Postgres error code of a unique constraint violation
+const uniqueViolation = "23505"
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txn is a transaction of a repository method: a database transaction of its
+// own, or a savepoint within the unit of work the store is bound to
+type txn interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+// Timeouts bound the time each kind of store operation may take, zero
+// meaning no bound
+type Timeouts struct {
+	// Read is the timeout of the lookups
+	Read time.Duration
+	// Write is the timeout of the changes
+	Write time.Duration
+	// Tx is the timeout of a whole unit of work
+	Tx time.Duration
+}
+
 // Store struct
 type Store struct {
-	db              *sql.DB
+	db *sql.DB
+	// q is db, or tx when the store is bound to a unit of work
+	q               querier
+	tx              *sql.Tx
+	timeouts        Timeouts
 	userRepository  *UserRepository
 	lobbyRepository *LobbyRepository
+	packRepository  *PackRepository
 }
 
 // New func
 func New(db *sql.DB) *Store {
 	return &Store{
 		db: db,
+		q:  db,
 	}
 }
 
@@ -47,3 +84,116 @@ func (s *Store) Lobby() store.LobbyRepository {
 
 	return s.lobbyRepository
 }
+
+// Pack func
+func (s *Store) Pack() store.PackRepository {
+	if s.packRepository != nil {
+		return s.packRepository
+	}
+
+	s.packRepository = &PackRepository{
+		store: s,
+	}
+
+	return s.packRepository
+}
+
+// SetTimeouts func
+func (s *Store) SetTimeouts(timeouts Timeouts) {
+	s.timeouts = timeouts
+}
+
+// WithTx runs fn in a database transaction, or in a savepoint if the store
+// is already bound to one. Notifications sent with pg_notify are delivered
+// by Postgres only once the transaction commits.
+func (s *Store) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	if s.tx != nil {
+		sp, err := s.begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer sp.Rollback()
+
+		if err := fn(s); err != nil {
+			return err
+		}
+
+		return sp.Commit()
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Tx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Store{db: s.db, q: tx, tx: tx, timeouts: s.timeouts}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// begin starts the transaction of a repository method
+func (s *Store) begin(ctx context.Context) (txn, error) {
+	if s.tx == nil {
+		return s.db.BeginTx(ctx, nil)
+	}
+
+	if _, err := s.tx.ExecContext(ctx, "SAVEPOINT repository"); err != nil {
+		return nil, err
+	}
+
+	return &savepoint{Tx: s.tx}, nil
+}
+
+// read bounds ctx by the timeout of the lookups
+func (s *Store) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.timeouts.Read)
+}
+
+// write bounds ctx by the timeout of the changes
+func (s *Store) write(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.timeouts.Write)
+}
+
+// withTimeout func
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// savepoint is a txn nested in a unit of work. Committing releases the
+// savepoint and rolling back undoes only the changes made since it.
+type savepoint struct {
+	*sql.Tx
+	done bool
+}
+
+// Commit func
+func (sp *savepoint) Commit() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+
+	_, err := sp.Exec("RELEASE SAVEPOINT repository")
+	return err
+}
+
+// Rollback func
+func (sp *savepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+
+	_, err := sp.Exec("ROLLBACK TO SAVEPOINT repository")
+	return err
+}
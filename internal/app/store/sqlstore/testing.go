@@ -7,7 +7,8 @@ import (
 	"testing"
 )
 
-// TestDB func
+// TestDB opens the test database migrated to the latest version, skipping
+// the test if there is no database to run it against
 func TestDB(t *testing.T, databaseURL string) (*sql.DB, func(...string)) {
 	t.Helper()
 
@@ -17,6 +18,16 @@ func TestDB(t *testing.T, databaseURL string) (*sql.DB, func(...string)) {
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("Postgres is not available: %v", err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Up(); err != nil {
 		t.Fatal(err)
 	}
 
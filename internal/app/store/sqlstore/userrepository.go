@@ -1,6 +1,7 @@
 package sqlstore
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -14,7 +15,10 @@ type UserRepository struct {
 }
 
 // Create func
-func (r *UserRepository) Create(u *model.User) error {
+func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
+
+	ctx, cancel := r.store.write(ctx)
+	defer cancel()
 
 	if result, varbool := u.Validate(); result["status"] == false && result["message"] == "Password must be longer" && varbool == false {
 		return errors.New("Password must be longer")
@@ -24,16 +28,19 @@ func (r *UserRepository) Create(u *model.User) error {
 		return errors.New("Unable to encrypt password")
 	}
 
-	return r.store.db.QueryRow("INSERT INTO users (login, password) VALUES ($1, $2) RETURNING id",
+	return r.store.q.QueryRowContext(ctx, "INSERT INTO users (login, password) VALUES ($1, $2) RETURNING id",
 		u.Login,
 		u.Password,
 	).Scan(&u.ID)
 }
 
 // Find func
-func (r *UserRepository) Find(id int) (*model.User, error) {
+func (r *UserRepository) Find(ctx context.Context, id int) (*model.User, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
 	u := &model.User{}
-	if err := r.store.db.QueryRow(
+	if err := r.store.q.QueryRowContext(ctx,
 		"SELECT id, login, password FROM users WHERE id = $1",
 		id,
 	).Scan(
@@ -51,9 +58,12 @@ func (r *UserRepository) Find(id int) (*model.User, error) {
 }
 
 // FindByLogin func
-func (r *UserRepository) FindByLogin(login string) (*model.User, error) {
+func (r *UserRepository) FindByLogin(ctx context.Context, login string) (*model.User, error) {
+	ctx, cancel := r.store.read(ctx)
+	defer cancel()
+
 	u := &model.User{}
-	if err := r.store.db.QueryRow(
+	if err := r.store.q.QueryRowContext(ctx,
 		"SELECT id, login, password FROM users WHERE login = $1",
 		login,
 	).Scan(
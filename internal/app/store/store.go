@@ -1,7 +1,14 @@
 package store
 
+import "context"
+
 //Store interface
 type Store interface {
 	User() UserRepository
 	Lobby() LobbyRepository
+	Pack() PackRepository
+	// WithTx runs fn as a single unit of work: the changes made through the
+	// Store passed to fn are kept only if fn returns nil, and lobby change
+	// notifications are sent once they are
+	WithTx(context.Context, func(Store) error) error
 }
@@ -24,11 +24,17 @@ func Error(w http.ResponseWriter, code int, err error) {
 	Respond(w, map[string]interface{}{"error": err.Error()})
 }
 
-// TokenGenerator func
-func TokenGenerator() string {
-	b := make([]byte, 3)
+// ErrorCode func
+func ErrorCode(w http.ResponseWriter, code int, errCode string, err error) {
+	w.WriteHeader(code)
+	Respond(w, map[string]interface{}{"error": err.Error(), "code": errCode})
+}
+
+// TokenGenerator returns a random token of length hex characters
+func TokenGenerator(length int) string {
+	b := make([]byte, (length+1)/2)
 	rand.Read(b)
-	return fmt.Sprintf("%x", b)
+	return fmt.Sprintf("%x", b)[:length]
 }
 
 // Contains func